@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cliscore/cmd/commands"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run dispatches args to the matching command and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	cmds := commands.GetCommands()
+
+	args, err := parseGlobalFlags(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return commands.ExitCode(err)
+	}
+
+	if len(args) == 0 {
+		printUsage(stderr, cmds)
+		return commands.ExitUsage
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			return printCommandHelp(stdout, stderr, cmds, args[1])
+		}
+		printUsage(stdout, cmds)
+		return commands.ExitOK
+	}
+
+	cmd := findCommand(cmds, args[0])
+	if cmd == nil {
+		fmt.Fprintf(stderr, "Unknown command: %s\n\n", args[0])
+		printUsage(stderr, cmds)
+		return commands.ExitUsage
+	}
+
+	err = cmd.Execute(args[1:])
+	code := commands.ExitCode(err)
+	if err != nil && code != commands.ExitOK {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+	}
+	return code
+}
+
+// parseGlobalFlags consumes the options that come before the command name
+// and returns the remaining arguments
+func parseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		switch name {
+		case "-o", "-output", "--output":
+		default:
+			return args, nil
+		}
+
+		if !hasValue {
+			if len(args) < 2 {
+				return nil, fmt.Errorf("%w: %s requires a format", commands.ErrUsage, name)
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		args = args[1:]
+
+		if err := commands.SetOutputFormat(value); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// findCommand returns the command registered under name, or nil
+func findCommand(cmds []commands.Command, name string) commands.Command {
+	for _, cmd := range cmds {
+		if cmd.Name() == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// printUsage renders the help index from the registered commands
+func printUsage(w io.Writer, cmds []commands.Command) {
+	width := 0
+	for _, cmd := range cmds {
+		if len(cmd.Name()) > width {
+			width = len(cmd.Name())
+		}
+	}
+
+	fmt.Fprintln(w, "Usage: cliscore [--output format] <command> [options] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Available commands:")
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.Name(), cmd.Description())
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Output formats: %s\n", strings.Join(commands.OutputFormats(), ", "))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'cliscore help <command>' for more information on a command.")
+}
+
+// printCommandHelp prints the usage and options of a single command
+func printCommandHelp(stdout, stderr io.Writer, cmds []commands.Command, name string) int {
+	cmd := findCommand(cmds, name)
+	if cmd == nil {
+		fmt.Fprintf(stderr, "Unknown command: %s\n\n", name)
+		printUsage(stderr, cmds)
+		return commands.ExitUsage
+	}
+
+	if err := commands.Help(cmd, stdout); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return commands.ExitCode(err)
+	}
+	return commands.ExitOK
+}
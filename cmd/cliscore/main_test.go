@@ -0,0 +1,773 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"cliscore/cmd/commands"
+)
+
+const testUUID = "ce1869f2-b922-456b-882c-58aa4ad5f266"
+
+// TestMain lets the test binary act as the cliscore executable so every
+// command runs end to end in a fresh process with its real exit code.
+func TestMain(m *testing.M) {
+	if os.Getenv("CLISCORE_TEST_MAIN") == "1" {
+		os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pagesize") != "" {
+			writeJSON(w, map[string]interface{}{
+				"pages": map[string]interface{}{"1": map[string]interface{}{"email": []string{"admin@example.com"}}},
+				"size":  1,
+				"took":  3,
+			})
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"results": map[string]interface{}{"email": []string{"admin@example.com"}},
+		})
+	})
+	mux.HandleFunc("/count/detailed", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"counts": map[string]int{"email": 1234}, "total_count": 1234, "took": 7})
+	})
+	mux.HandleFunc("/credits", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body["apiKey"] {
+		case "expired-key":
+			w.Header().Set("X-Request-Id", "req-123")
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(w, map[string]string{"error": "API key expired"})
+		case "broke-key":
+			w.WriteHeader(http.StatusPaymentRequired)
+			writeJSON(w, map[string]string{"error": "insufficient credits"})
+		case "busy-key":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			writeJSON(w, map[string]interface{}{"credits": 42})
+		}
+	})
+	mux.HandleFunc("/machineinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("uuid") != testUUID {
+			http.Error(w, `{"error":"log not found"}`, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"data": map[string]interface{}{"operatingSystem": "Windows 10"}})
+	})
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("PK archive bytes"))
+	})
+	mux.HandleFunc("/validate", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["apiKey"] != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// cliCommand prepares the test binary to run as cliscore inside dir against srv
+func cliCommand(srv *httptest.Server, dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"CLISCORE_TEST_MAIN=1",
+		"HOME="+dir,
+		"CLISCORE_BASE_URL="+srv.URL,
+		"CLISCORE_API_KEY=test-key",
+		"CLISCORE_SPINNER_STYLE=none",
+		"CLISCORE_SAVE_RESULTS=false",
+		"CLISCORE_MAX_RETRIES=-1",
+	)
+	return cmd
+}
+
+// runCLI executes cliscore with args and returns its output and exit code
+func runCLI(t *testing.T, srv *httptest.Server, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+
+	cmd := cliCommand(srv, t.TempDir(), args...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return stdout.String(), stderr.String(), 0
+	case errors.As(err, &exitErr):
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	default:
+		t.Fatalf("running cliscore %v: %v", args, err)
+		return "", "", -1
+	}
+}
+
+func TestRun_Commands(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout []string
+		wantStderr []string
+	}{
+		{name: "no arguments", args: nil, wantCode: 2, wantStderr: []string{"Usage: cliscore", "search"}},
+		{name: "help index", args: []string{"help"}, wantCode: 0, wantStdout: []string{"Available commands:", "credits", "Check your remaining credits"}},
+		{name: "help flag", args: []string{"--help"}, wantCode: 0, wantStdout: []string{"Available commands:"}},
+		{name: "help command", args: []string{"help", "count"}, wantCode: 0, wantStdout: []string{"cliscore count", "Count occurrences of terms", "Options:", "-types string"}},
+		{name: "help unknown command", args: []string{"help", "nope"}, wantCode: 2, wantStderr: []string{"Unknown command: nope"}},
+		{name: "unknown command", args: []string{"nope"}, wantCode: 2, wantStderr: []string{"Unknown command: nope", "Available commands:"}},
+		{name: "search", args: []string{"search", "admin@example.com"}, wantCode: 0, wantStdout: []string{"Found 1 results", "admin@example.com"}},
+		{name: "search quiet", args: []string{"search", "-quiet", "admin@example.com"}, wantCode: 0, wantStdout: []string{"1\n"}},
+		{name: "search paginated", args: []string{"search", "-page-size", "10", "admin@example.com"}, wantCode: 0, wantStdout: []string{"Pages retrieved: 1", "=== Page 1 ==="}},
+		{name: "search all pages", args: []string{"search", "-all", "-page-size", "10", "admin@example.com"}, wantCode: 0, wantStdout: []string{"Pages retrieved: 1", "=== Page 1 ==="}, wantStderr: []string{"Fetched page 1 of 1"}},
+		{name: "search all with page", args: []string{"search", "-all", "-page", "2", "admin@example.com"}, wantCode: 2, wantStderr: []string{"-all cannot be combined"}},
+		{name: "search stream", args: []string{"search", "-stream", "admin@example.com"}, wantCode: 0, wantStdout: []string{`{"group":"email","value":"admin@example.com"}` + "\n"}, wantStderr: []string{"Streamed 1 records"}},
+		{name: "search stream paginated", args: []string{"search", "-stream", "-page-size", "10", "admin@example.com"}, wantCode: 0, wantStdout: []string{`{"page":1,"group":"email","value":"admin@example.com"}`}, wantStderr: []string{"(1 results in total)"}},
+		{name: "search stream with all", args: []string{"search", "-stream", "-all", "admin@example.com"}, wantCode: 2, wantStderr: []string{"-stream cannot be combined"}},
+		{name: "search stream csv", args: []string{"search", "-stream", "-output", "csv", "admin@example.com"}, wantCode: 2, wantStderr: []string{"-stream only supports ndjson"}},
+		{name: "search csv", args: []string{"search", "-output", "csv", "admin@example.com"}, wantCode: 0, wantStdout: []string{"group,value\nemail,admin@example.com\n"}},
+		{name: "search global output", args: []string{"--output", "ndjson", "search", "-page-size", "10", "admin@example.com"}, wantCode: 0, wantStdout: []string{`{"page":1,"group":"email","value":"admin@example.com"}`}},
+		{name: "search table", args: []string{"-o=table", "search", "admin@example.com"}, wantCode: 0, wantStdout: []string{"GROUP  VALUE\nemail  admin@example.com\n"}},
+		{name: "search unknown output", args: []string{"search", "-output", "xml", "admin@example.com"}, wantCode: 2, wantStderr: []string{`unknown output format "xml"`}},
+		{name: "global output missing format", args: []string{"--output"}, wantCode: 2, wantStderr: []string{"--output requires a format"}},
+		{name: "search fields", args: []string{"search", "-fields", "group", "-output", "csv", "admin@example.com"}, wantCode: 0, wantStdout: []string{"group\nemail\n"}},
+		{name: "search filter match", args: []string{"search", "-filter", `value =~ "@example\.com$"`, "-output", "ndjson", "admin@example.com"}, wantCode: 0, wantStdout: []string{`{"group":"email","value":"admin@example.com"}`}},
+		{name: "search filter text", args: []string{"search", "-filter", "group == url", "admin@example.com"}, wantCode: 0, wantStdout: []string{"Found 1 results, 0 selected", "[]"}},
+		{name: "search filter stream", args: []string{"search", "-stream", "-filter", "group != email", "admin@example.com"}, wantCode: 0, wantStderr: []string{"Streamed 0 records"}},
+		{name: "search bad filter", args: []string{"search", "-filter", "group ==", "admin@example.com"}, wantCode: 2, wantStderr: []string{"invalid filter at position"}},
+		{name: "search detected types without prompt", args: []string{"search", "admin@example.com"}, wantCode: 0, wantStderr: []string{"Using detected types: login"}},
+		{name: "search types flag", args: []string{"search", "-types", "login,url", "-quiet", "anything"}, wantCode: 0, wantStdout: []string{"1\n"}},
+		{name: "search unknown type", args: []string{"search", "-types", "login,bogus", "anything"}, wantCode: 2, wantStderr: []string{`unknown type "bogus"`}},
+		{name: "search undetectable term", args: []string{"search", "-yes", "anything"}, wantCode: 2, wantStderr: []string{"could not detect the type of anything", "-types"}},
+		{name: "search ip", args: []string{"search", "-quiet", "10.0.0.1"}, wantCode: 0, wantStderr: []string{"Using detected types: ip"}},
+		{name: "search cidr", args: []string{"search", "10.1.0.0/23"}, wantCode: 0, wantStderr: []string{"Using detected types: ip", "Searching 10.1.0.0/23 as 2 term(s)"}},
+		{name: "search cidr too large", args: []string{"search", "-cidr", "expand", "10.0.0.0/8"}, wantCode: 2, wantStderr: []string{"more than the limit of 256"}},
+		{name: "search hash", args: []string{"search", "-quiet", "5f4dcc3b5aa765d61d8327deb882cf99"}, wantCode: 0, wantStderr: []string{"Using detected types: hash", "Hash 5f4dcc3b5aa765d61d8327deb882cf99: likely MD5, NTLM, MD4"}},
+		{name: "search phone", args: []string{"search", "-quiet", "-country-code", "44", "07911 123456"}, wantCode: 0, wantStderr: []string{"Using detected types: phone", "Phone 07911 123456: +447911123456"}},
+		{name: "search defanged", args: []string{"search", "-output", "ndjson", "admin[@]example[.]com"}, wantCode: 0, wantStdout: []string{`{"term":"admin@example.com","input":"admin[@]example[.]com","type":"login","group":"email","value":"admin@example.com"}`}, wantStderr: []string{"Normalized admin[@]example[.]com as admin@example.com", "Using detected types: login for admin[@]example[.]com"}},
+		{name: "search idn", args: []string{"search", "münchen.de"}, wantCode: 0, wantStderr: []string{"Using detected types: email_domain for münchen.de", "Normalized münchen.de as xn--mnchen-3ya.de"}},
+		{name: "search without terms", args: []string{"search"}, wantCode: 2, wantStderr: []string{"search term is required"}},
+		{name: "search bad flag", args: []string{"search", "-bogus", "x"}, wantCode: 2, wantStderr: []string{"flag provided but not defined"}},
+		{name: "count", args: []string{"count", "admin@example.com"}, wantCode: 0, wantStdout: []string{"Count Results: 1,234", "email: 1,234"}},
+		{name: "count tsv", args: []string{"count", "-output", "tsv", "admin@example.com"}, wantCode: 0, wantStdout: []string{"type\tcount\nemail\t1234\n"}},
+		{name: "count yaml", args: []string{"--output", "yaml", "count", "admin@example.com"}, wantCode: 0, wantStdout: []string{"counts:\n  email: 1234\ntook: 7\ntotal_count: 1234\n"}},
+		{name: "count types flag", args: []string{"count", "-no-prompt", "-types", "email_domain", "example"}, wantCode: 0, wantStdout: []string{"Count Results: 1,234"}},
+		{name: "count per-term types", args: []string{"count", "admin@example.com", "example.com"}, wantCode: 0, wantStdout: []string{"Count Results: 2,468", "  admin@example.com:\n    email: 1,234\n  example.com:\n    email: 1,234\n"}},
+		{name: "count per-term types csv", args: []string{"count", "-output", "csv", "admin@example.com", "example.com"}, wantCode: 0, wantStdout: []string{"term,type,count\nadmin@example.com,email,1234\nexample.com,email,1234\n"}},
+		{name: "count input stdin", args: []string{"count", "-input", "-", "-output", "csv"}, stdin: "# weekly review\nadmin@example.com\nexample.com\nadmin@example.com\n", wantCode: 0, wantStdout: []string{
+			"term,types,status,results,file\nadmin@example.com,login,found,1234,0001_admin_example_com.json\nexample.com,email_domain,found,1234,0002_example_com.json\n"}},
+		{name: "count input missing file", args: []string{"count", "-input", "missing.txt"}, wantCode: 2, wantStderr: []string{"error opening input"}},
+		{name: "count without terms", args: []string{"count"}, wantCode: 2},
+		{name: "detect", args: []string{"detect", "admin@example.com", "555.123.4567"}, wantCode: 0, wantStdout: []string{"TERM", "admin@example.com  login", "555.123.4567       phone"}},
+		{name: "detect all ndjson", args: []string{"detect", "-all", "-output", "ndjson", "example.com"}, wantCode: 0, wantStdout: []string{
+			`{"term":"example.com","type":"email_domain","confidence":0.8,"rule":"domain"}` + "\n" +
+				`{"term":"example.com","type":"url","confidence":0.3,"rule":"url","detail":"bare host"}` + "\n"}},
+		{name: "detect defanged idn", args: []string{"detect", "-output", "ndjson", "hxxps://bücher[.]example/x"}, wantCode: 0, wantStdout: []string{
+			`{"input":"hxxps://bücher[.]example/x","term":"https://xn--bcher-kva.example/x","unicode":"https://bücher.example/x","type":"url","confidence":0.95,"rule":"url"}`}},
+		{name: "detect without terms", args: []string{"detect"}, wantCode: 2},
+		{name: "credits", args: []string{"credits"}, wantCode: 0, wantStdout: []string{"Credits remaining: 42"}},
+		{name: "credits quiet", args: []string{"credits", "-quiet"}, wantCode: 0, wantStdout: []string{"42\n"}},
+		{name: "credits json", args: []string{"credits", "-output", "json"}, wantCode: 0, wantStdout: []string{"{\n  \"credits\": 42\n}\n"}},
+		{name: "credits unauthorized", args: []string{"credits", "-api-key", "expired-key"}, wantCode: 3, wantStderr: []string{"HTTP 401: API key expired", "req-123"}},
+		{name: "credits exhausted", args: []string{"credits", "-api-key", "broke-key"}, wantCode: 4},
+		{name: "credits rate limited", args: []string{"credits", "-api-key", "busy-key"}, wantCode: 5},
+		{name: "machineinfo", args: []string{"machineinfo", testUUID}, wantCode: 0, wantStdout: []string{"Machine Information:", "Windows 10"}},
+		{name: "machineinfo yaml", args: []string{"--output", "yaml", "machineinfo", testUUID}, wantCode: 0, wantStdout: []string{"Windows 10"}},
+		{name: "machineinfo not found", args: []string{"machineinfo", "00000000-0000-0000-0000-000000000000"}, wantCode: 6, wantStderr: []string{"HTTP 404: log not found"}},
+		{name: "machineinfo without uuid", args: []string{"machineinfo"}, wantCode: 2},
+		{name: "download", args: []string{"download", testUUID}, wantCode: 0, wantStdout: []string{"File downloaded successfully: " + testUUID + ".zip"}},
+		{name: "download without uuid", args: []string{"download"}, wantCode: 2},
+		{name: "config", args: []string{"config"}, wantCode: 0, wantStdout: []string{"Current configuration:", "Base URL: " + srv.URL}},
+		{name: "spinner", args: []string{"spinner"}, wantCode: 0, wantStdout: []string{"Available spinner styles:"}},
+		{name: "setup", args: []string{"setup"}, stdin: "\ntest-key\nn\n13\n", wantCode: 0, wantStdout: []string{"Configuration saved successfully"}},
+		{name: "setup invalid key", args: []string{"setup"}, stdin: "\nwrong-key\n", wantCode: 3, wantStderr: []string{"invalid API key"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCLI(t, srv, tt.stdin, tt.args...)
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, expected %d\nstdout:\n%s\nstderr:\n%s", code, tt.wantCode, stdout, stderr)
+			}
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout missing %q\nstdout:\n%s", want, stdout)
+				}
+			}
+			for _, want := range tt.wantStderr {
+				if !strings.Contains(stderr, want) {
+					t.Errorf("stderr missing %q\nstderr:\n%s", want, stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_DownloadWritesFile(t *testing.T) {
+	srv := newTestServer(t)
+
+	dir := t.TempDir()
+	cmd := cliCommand(srv, dir, "download", testUUID)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("download failed: %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, testUUID+".zip"))
+	if err != nil {
+		t.Fatalf("reading downloaded file: %v", err)
+	}
+	if string(data) != "PK archive bytes" {
+		t.Errorf("downloaded content = %q, expected %q", data, "PK archive bytes")
+	}
+}
+
+func TestRun_DetectCustomRule(t *testing.T) {
+	srv := newTestServer(t)
+
+	dir := t.TempDir()
+	configDir := filepath.Join(dir, ".keyscore-cli")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"detectionRules": [{"name": "staff-id", "type": "username", "pattern": "^emp-[0-9]{6}$", "confidence": 0.9}]}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cliCommand(srv, dir, "detect", "-output", "csv", "emp-123456").CombinedOutput()
+	if err != nil {
+		t.Fatalf("detect failed: %v\n%s", err, out)
+	}
+	if want := "emp-123456,username,0.9,staff-id\n"; !strings.Contains(string(out), want) {
+		t.Errorf("output %q is missing %q", out, want)
+	}
+}
+
+func TestRun_SearchGroupsTermsByType(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Terms []string `json:"terms"`
+			Types []string `json:"types"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		requests = append(requests, strings.Join(body.Terms, ",")+" as "+strings.Join(body.Types, ","))
+		mu.Unlock()
+
+		results := map[string]interface{}{}
+		for _, typ := range body.Types {
+			results[typ] = []string{"found " + body.Terms[0]}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	stdout, stderr, code := runCLI(t, srv, "", "search", "-output", "ndjson", "alice@corp.com", "corp.com", "10.1.2.3")
+	if code != 0 {
+		t.Fatalf("exit code = %d\nstderr:\n%s", code, stderr)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(requests)
+	expected := []string{"10.1.2.3 as ip", "alice@corp.com as email", "corp.com as email_domain"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("requests = %v, expected %v", requests, expected)
+	}
+
+	want := `{"term":"alice@corp.com","type":"login","group":"email","value":"found alice@corp.com"}` + "\n" +
+		`{"term":"corp.com","type":"email_domain","group":"email_domain","value":"found corp.com"}` + "\n" +
+		`{"term":"10.1.2.3","type":"ip","group":"ip","value":"found 10.1.2.3"}` + "\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, expected %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "Using detected types: login for alice@corp.com") {
+		t.Errorf("stderr %q is missing the per-term types", stderr)
+	}
+}
+
+func TestRun_SearchKeepsLiteralTerms(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Terms []string `json:"terms"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		requests = append(requests, body.Terms...)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": map[string]interface{}{}})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	// Passwords only look like defanged or mixed-case hosts
+	_, stderr, code := runCLI(t, srv, "", "search", "-types", "password", "MyPass.Word1", "p[.]ss(at)x")
+	if code != 0 {
+		t.Fatalf("exit code = %d\nstderr:\n%s", code, stderr)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if expected := []string{"MyPass.Word1", "p[.]ss(at)x"}; !reflect.DeepEqual(requests, expected) {
+		t.Errorf("searched terms = %v, expected the passwords unchanged %v", requests, expected)
+	}
+	if strings.Contains(stderr, "Normalized") {
+		t.Errorf("stderr %q reports normalizing a password", stderr)
+	}
+}
+
+func TestRun_SearchBatchInput(t *testing.T) {
+	srv := newTestServer(t)
+
+	dir := t.TempDir()
+	input := "term,owner\nadmin@example.com,it\nexample.com,it\nhello,nobody\nadmin@example.com,it\n"
+	if err := os.WriteFile(filepath.Join(dir, "terms.csv"), []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := cliCommand(srv, dir, "search", "-input", "terms.csv", "-input-column", "term", "-batch-size", "1", "-results-dir", "out")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("search failed: %v\n%s", err, stderr.String())
+	}
+
+	for _, want := range []string{"admin@example.com  login         found    1", "hello                            skipped"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("summary missing %q\n%s", want, stdout.String())
+		}
+	}
+	if want := "Processed 3 terms in 2 requests: 2 with results, 0 without, 0 failed, 1 skipped"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("stderr missing %q\n%s", want, stderr.String())
+	}
+
+	batches, err := filepath.Glob(filepath.Join(dir, "out", "search-batch-*"))
+	if err != nil || len(batches) != 1 {
+		t.Fatalf("expected one batch directory, found %v (%v)", batches, err)
+	}
+	data, err := os.ReadFile(filepath.Join(batches[0], "0001_admin_example_com.json"))
+	if err != nil {
+		t.Fatalf("reading per-term file: %v", err)
+	}
+	var file struct {
+		Term    string                   `json:"term"`
+		Results []map[string]interface{} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil || file.Term != "admin@example.com" || len(file.Results) != 1 {
+		t.Errorf("per-term file = %s (%v), expected one result for admin@example.com", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(batches[0], "summary.json")); err != nil {
+		t.Errorf("summary.json: %v", err)
+	}
+}
+
+func TestRun_HelpForEveryCommand(t *testing.T) {
+	for _, cmd := range commands.GetCommands() {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"help", cmd.Name()}, &stdout, &stderr)
+		if code != 0 {
+			t.Errorf("help %s: exit code = %d, expected 0", cmd.Name(), code)
+		}
+		if !strings.Contains(stdout.String(), cmd.Description()) {
+			t.Errorf("help %s: output %q is missing the description", cmd.Name(), stdout.String())
+		}
+	}
+}
+
+func TestRun_JobsResumeInterruptedBatch(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	broke := true
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Terms []string `json:"terms"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, strings.Join(body.Terms, ","))
+		if broke && body.Terms[0] == "bob@corp.com" {
+			w.WriteHeader(http.StatusPaymentRequired)
+			json.NewEncoder(w).Encode(map[string]string{"error": "insufficient credits"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": map[string]interface{}{"email": []string{"found " + body.Terms[0]}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "terms.txt"), []byte("alice@corp.com\nbob@corp.com\ncorp.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, string, int) {
+		t.Helper()
+		cmd := cliCommand(srv, dir, args...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return stdout.String(), stderr.String(), exitErr.ExitCode()
+		}
+		if err != nil {
+			t.Fatalf("running cliscore %v: %v", args, err)
+		}
+		return stdout.String(), stderr.String(), 0
+	}
+
+	// Running out of credits stops the batch after bob@corp.com
+	_, stderr, code := run("search", "-input", "terms.txt", "-batch-size", "1", "-parallel", "1", "-api-key", "secret", "-results-dir", "out")
+	if code != 4 {
+		t.Fatalf("exit code = %d, expected 4\nstderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stderr, "stopped with 2 terms remaining; resume with: cliscore jobs resume ") {
+		t.Errorf("stderr %q is missing the resume hint", stderr)
+	}
+
+	stdout, stderr, code := run("jobs", "list", "-output", "json")
+	if code != 0 {
+		t.Fatalf("jobs list: exit code = %d\nstderr:\n%s", code, stderr)
+	}
+	var list []struct {
+		ID     string   `json:"id"`
+		Status string   `json:"status"`
+		Args   []string `json:"args"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &list); err != nil || len(list) != 1 {
+		t.Fatalf("jobs list = %s (%v), expected one job", stdout, err)
+	}
+	job := list[0]
+	if job.Status != "interrupted" {
+		t.Errorf("job status = %q, expected interrupted", job.Status)
+	}
+	if strings.Contains(strings.Join(job.Args, " "), "secret") {
+		t.Errorf("job args %q keep the API key", job.Args)
+	}
+
+	mu.Lock()
+	broke = false
+	requests = nil
+	mu.Unlock()
+
+	if _, stderr, code := run("jobs", "resume", job.ID); code != 0 {
+		t.Fatalf("jobs resume: exit code = %d\nstderr:\n%s", code, stderr)
+	}
+	expected := []string{"bob@corp.com", "corp.com"}
+	mu.Lock()
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("resumed requests = %v, expected only the remaining terms %v", requests, expected)
+	}
+	mu.Unlock()
+
+	stdout, _, code = run("jobs", "status", job.ID)
+	if code != 0 || !strings.Contains(stdout, "Status: completed") || !strings.Contains(stdout, "Terms: 3 done of 3") {
+		t.Errorf("jobs status = %d\n%s", code, stdout)
+	}
+	if _, _, code := run("jobs", "resume", job.ID); code != 2 {
+		t.Errorf("resuming a completed job: exit code = %d, expected 2", code)
+	}
+	if _, _, code := run("jobs", "cancel", "missing"); code != 6 {
+		t.Errorf("cancelling an unknown job: exit code = %d, expected 6", code)
+	}
+}
+
+func TestRun_SearchCache(t *testing.T) {
+	var requests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": map[string]interface{}{"email": []string{"admin@example.com"}},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	run := func(args ...string) (string, string) {
+		t.Helper()
+		cmd := cliCommand(srv, dir, args...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("cliscore %v: %v\n%s", args, err, stderr.String())
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	tests := []struct {
+		args     []string
+		requests int32
+		cached   bool
+	}{
+		{[]string{"search", "-output", "json", "admin@example.com"}, 1, false},
+		{[]string{"search", "-output", "csv", "admin@example.com"}, 1, true},
+		{[]string{"search", "-refresh", "admin@example.com"}, 2, false},
+		{[]string{"search", "-no-cache", "admin@example.com"}, 3, false},
+		{[]string{"search", "-types", "login", "admin@example.com"}, 3, true},
+		{[]string{"search", "-wildcard", "admin@example.com"}, 4, false},
+	}
+	for _, test := range tests {
+		_, stderr := run(test.args...)
+		if n := atomic.LoadInt32(&requests); n != test.requests {
+			t.Errorf("%v: %d requests sent in total, expected %d", test.args, n, test.requests)
+		}
+		if cached := strings.Contains(stderr, "Served from cache"); cached != test.cached {
+			t.Errorf("%v: served from cache = %v, expected %v\nstderr:\n%s", test.args, cached, test.cached, stderr)
+		}
+	}
+
+	stdout, _ := run("cache", "stats", "-output", "json")
+	var stats struct {
+		Entries int `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &stats); err != nil || stats.Entries != 2 {
+		t.Errorf("cache stats = %s (%v), expected 2 entries", stdout, err)
+	}
+	if stdout, _ := run("cache", "clear"); !strings.Contains(stdout, "Removed 2 cached responses") {
+		t.Errorf("cache clear = %q", stdout)
+	}
+	run("search", "admin@example.com")
+	if n := atomic.LoadInt32(&requests); n != 5 {
+		t.Errorf("search after clearing the cache: %d requests sent in total, expected 5", n)
+	}
+}
+
+func TestRun_DownloadListAndExtract(t *testing.T) {
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for name, content := range map[string]string{"Passwords.txt": "secret", "../escape.txt": "x"} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	w.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive.Bytes())
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	cmd := cliCommand(srv, dir, "--output", "csv", "download", "-list", "-extract", "out", testUUID)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("download failed: %v\n%s", err, stderr.String())
+	}
+
+	if !strings.HasPrefix(stdout.String(), "name,size,compressed,modified\n") || !strings.Contains(stdout.String(), "\nPasswords.txt,6,") {
+		t.Errorf("listing = %q, expected a CSV row for Passwords.txt", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "\nname,reason\n../escape.txt,path escapes the target directory\n") {
+		t.Errorf("extraction report %q is missing the refused entry", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "File downloaded successfully") {
+		t.Errorf("stderr %q is missing the download status", stderr.String())
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "out", "Passwords.txt")); err != nil || string(data) != "secret" {
+		t.Errorf("extracted file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("an entry was extracted outside the target directory")
+	}
+}
+
+func TestRun_DownloadMany(t *testing.T) {
+	const missingUUID = "0b0e4c1e-58a2-4d4f-9a0e-3c1f1f6f0d2a"
+	var requests int32
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Query().Get("uuid") != testUUID {
+			http.Error(w, `{"error":"log not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("PK archive bytes"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	stdin := `{"uuid":"` + strings.ToUpper(testUUID) + `","email":"admin@example.com"}` + "\n" +
+		`{"uuid":"` + testUUID + `"}` + "\n" + missingUUID + "\n"
+	download := func() (string, string, int) {
+		cmd := cliCommand(srv, dir, "--output", "json", "download", "-input", "-", "-output-dir", "logs", "-skip-existing")
+		cmd.Stdin = strings.NewReader(stdin)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		cmd.Run()
+		return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+	}
+
+	readManifest := func() map[string]string {
+		data, err := os.ReadFile(filepath.Join(dir, "logs", "manifest.json"))
+		if err != nil {
+			t.Fatalf("reading manifest: %v", err)
+		}
+		var manifest struct {
+			Downloads []struct {
+				UUID   string `json:"uuid"`
+				Status string `json:"status"`
+			} `json:"downloads"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			t.Fatalf("decoding manifest: %v", err)
+		}
+		statuses := make(map[string]string)
+		for _, entry := range manifest.Downloads {
+			statuses[entry.UUID] = entry.Status
+		}
+		return statuses
+	}
+
+	stdout, stderr, code := download()
+	if code != 1 {
+		t.Fatalf("exit code = %d, expected 1 for the missing log\nstderr: %s", code, stderr)
+	}
+	if want := map[string]string{testUUID: "downloaded", missingUUID: "failed"}; !reflect.DeepEqual(readManifest(), want) {
+		t.Errorf("manifest = %v, expected %v", readManifest(), want)
+	}
+	if !strings.Contains(stdout, `"downloaded": 1`) || !strings.Contains(stderr, "1 of 2 downloads failed") {
+		t.Errorf("output = %q / %q, expected the manifest and the failure count", stdout, stderr)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "logs", testUUID, testUUID+".zip")); err != nil || string(data) != "PK archive bytes" {
+		t.Errorf("downloaded file = %q, %v", data, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("first run sent %d requests, expected 2 without retrying the missing log", got)
+	}
+
+	download()
+	if want := map[string]string{testUUID: "skipped", missingUUID: "failed"}; !reflect.DeepEqual(readManifest(), want) {
+		t.Errorf("manifest after rerun = %v, expected %v", readManifest(), want)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("sent %d requests in total, expected the verified log to be skipped", got)
+	}
+}
+
+func TestRun_DownloadManyRetriesOnce(t *testing.T) {
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, `{"error":"unavailable"}`, http.StatusServiceUnavailable)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	cmd := cliCommand(srv, dir, "download", "-quiet", "-retries", "1", "-output-dir", "logs", testUUID, "0b0e4c1e-58a2-4d4f-9a0e-3c1f1f6f0d2a")
+	// Client retries must not multiply with -retries
+	cmd.Env = append(cmd.Env, "CLISCORE_MAX_RETRIES=3")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Run()
+
+	if code := cmd.ProcessState.ExitCode(); code != 1 {
+		t.Fatalf("exit code = %d, expected 1\nstderr: %s", code, stderr.String())
+	}
+	if got := atomic.LoadInt32(&requests); got != 4 {
+		t.Errorf("sent %d requests, expected 2 attempts for each of 2 logs", got)
+	}
+	if stdout.Len() > 0 || strings.Contains(stderr.String(), "manifest written") {
+		t.Errorf("output = %q / %q, expected -quiet to print no rows or summary", stdout.String(), stderr.String())
+	}
+}
+
+func TestRun_CommonFlagsSaveResults(t *testing.T) {
+	srv := newTestServer(t)
+
+	for _, args := range [][]string{
+		{"search", "-types", "email", "admin@example.com"},
+		{"count", "-types", "email", "admin@example.com"},
+		{"credits"},
+		{"machineinfo", testUUID},
+		{"download", testUUID},
+	} {
+		t.Run(args[0], func(t *testing.T) {
+			dir := t.TempDir()
+			cmdArgs := append([]string{args[0], "-quiet", "-save", "-results-dir", "saved", "-api-key", "test-key"}, args[1:]...)
+			cmd := cliCommand(srv, dir, cmdArgs...)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%v failed: %v\n%s", cmdArgs, err, out)
+			}
+
+			saved, err := filepath.Glob(filepath.Join(dir, "saved", args[0]+"_*.json"))
+			if err != nil || len(saved) != 1 {
+				t.Errorf("saved results = %v, %v, expected one %s file despite CLISCORE_SAVE_RESULTS=false", saved, err, args[0])
+			}
+		})
+	}
+}
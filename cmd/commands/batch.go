@@ -0,0 +1,510 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cliscore/internal/batch"
+	"cliscore/internal/config"
+	"cliscore/internal/jobs"
+	"cliscore/pkg/keyscore"
+)
+
+// Batch defaults for -batch-size and -parallel
+const (
+	defaultBatchSize     = 100
+	defaultBatchParallel = 4
+)
+
+// batchFlags holds the flags that read terms from a file or stdin or resume
+// a batch job, and the job store
+type batchFlags struct {
+	store    *jobs.Store
+	job      *jobs.Job // The job loaded by -resume
+	resume   string
+	input    string
+	format   string
+	column   string
+	size     int
+	parallel int
+}
+
+// addBatchFlags registers the batch input flags on flagSet. chunked adds
+// -batch-size for commands that send several terms per request.
+func addBatchFlags(flagSet *flag.FlagSet, chunked bool) *batchFlags {
+	b := &batchFlags{size: 1}
+	flagSet.StringVar(&b.input, "input", "", "Read terms from a file, or - for stdin (one per line, a CSV column or a JSON array)")
+	flagSet.StringVar(&b.format, "input-format", batch.FormatAuto, "Format of -input: auto, lines, csv or json")
+	flagSet.StringVar(&b.column, "input-column", "", "CSV column (name or 1-based index) or JSON field holding the terms")
+	if chunked {
+		flagSet.IntVar(&b.size, "batch-size", defaultBatchSize, "Terms sent per request with -input")
+	}
+	flagSet.IntVar(&b.parallel, "parallel", defaultBatchParallel, "Requests run in parallel with -input")
+	flagSet.StringVar(&b.resume, "resume", "", "Resume the batch job with this ID (see cliscore jobs)")
+	return b
+}
+
+// enabled reports whether terms are run as a batch job
+func (b *batchFlags) enabled() bool {
+	return b.input != "" || b.resume != ""
+}
+
+// readTerms returns args followed by the terms read from -input, without
+// duplicates. Resumed jobs take their terms from the job instead.
+func (b *batchFlags) readTerms(args []string) ([]string, error) {
+	if b.size < 1 || b.parallel < 1 {
+		return nil, usageError("-batch-size and -parallel must be at least 1")
+	}
+	if b.resume != "" {
+		return nil, nil
+	}
+	terms, err := batch.ReadFile(b.input, batch.Options{Format: b.format, Column: b.column})
+	if err != nil {
+		return nil, usageError("%v", err)
+	}
+	return batch.Dedupe(append(append([]string{}, args...), terms...)), nil
+}
+
+// resolveBatchTypes is ResolveTermTypes for batches: it never prompts, and
+// terms whose type cannot be detected are skipped rather than failing the
+// whole batch
+func resolveBatchTypes(terms []string, typesFlag string, detector Detector) ([]TermGroup, []string, error) {
+	if typesFlag != "" {
+		types, err := parseTypes(typesFlag)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []TermGroup{{Terms: terms, Types: types}}, nil, nil
+	}
+	groups, undetected := groupTerms(terms, detector)
+	return groups, undetected, nil
+}
+
+// chunkGroups splits every group into groups of at most size terms, each
+// sent as one request
+func chunkGroups(groups []TermGroup, size int) []TermGroup {
+	var chunked []TermGroup
+	for _, group := range groups {
+		for _, chunk := range batch.Chunk(group.Terms, size) {
+			chunked = append(chunked, TermGroup{Terms: chunk, Types: group.Types})
+		}
+	}
+	return chunked
+}
+
+// batchColumns lead every row of a batch summary
+var batchColumns = []string{"term", "input", "types", "status", "results", "error", "file"}
+
+// jobArgs returns args without the flags a job manifest must not keep: the
+// API key, which is read from the config again on resume, and -resume
+func jobArgs(args []string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(kept, args[i:]...)
+		}
+		name := strings.TrimLeft(arg, "-")
+		if !strings.HasPrefix(arg, "-") || name == "" {
+			kept = append(kept, arg)
+			continue
+		}
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name = name[:eq]
+		} else if name == "api-key" || name == "resume" {
+			i++ // Skip the value too
+		}
+		if name != "api-key" && name != "resume" {
+			kept = append(kept, arg)
+		}
+	}
+	return kept
+}
+
+// resolve returns the term groups of a batch, in requests of at most -batch-size
+// terms, and the terms skipped because their type is unknown. With -resume
+// the groups hold the remaining terms of the job instead, along with the
+// original inputs of the job.
+func (b *batchFlags) resolve(command string, terms []string, typesFlag string, detector Detector) ([]TermGroup, []string, map[string]string, error) {
+	store, err := jobs.DefaultStore()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	b.store = store
+
+	if b.resume != "" {
+		job, groups, inputs, err := resumeBatchJob(store, b.resume, command, b.size)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		b.job = job
+		return groups, nil, inputs, nil
+	}
+
+	groups, skipped, err := resolveBatchTypes(terms, typesFlag, detector)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return chunkGroups(groups, b.size), skipped, nil, nil
+}
+
+// start returns the job a batch checkpoints to: the resumed job, or a new
+// job recording args and the resolved groups
+func (b *batchFlags) start(command string, args []string, groups []TermGroup, skipped []string, inputs map[string]string, resultsDir string) (*jobs.Job, error) {
+	if b.job != nil {
+		return b.job, nil
+	}
+	// Jobs can be resumed from any directory
+	dir, err := filepath.Abs(batchDir(resultsDir, command))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve results directory: %v", err)
+	}
+	return newBatchJob(b.store, command, args, groups, skipped, inputs, dir)
+}
+
+// newBatchJob records a new batch so it can be resumed
+func newBatchJob(store *jobs.Store, command string, args []string, groups []TermGroup, skipped []string, inputs map[string]string, dir string) (*jobs.Job, error) {
+	var terms []jobs.Term
+	for _, group := range groups {
+		for _, term := range group.Terms {
+			terms = append(terms, jobs.Term{Term: term, Input: inputs[term], Types: group.Types, Status: jobs.TermPending})
+		}
+	}
+	for _, term := range skipped {
+		terms = append(terms, jobs.Term{Term: term, Input: inputs[term], Status: jobs.TermSkipped, Error: "type could not be detected; pass -types"})
+	}
+	return store.Create(command, jobArgs(args), dir, terms)
+}
+
+// resumeBatchJob loads job id and groups its remaining terms by type into
+// requests of at most size terms
+func resumeBatchJob(store *jobs.Store, id, command string, size int) (*jobs.Job, []TermGroup, map[string]string, error) {
+	job, err := store.Load(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if job.Command != command {
+		return nil, nil, nil, usageError("job %s is a %s job; resume it with cliscore jobs resume %s", id, job.Command, id)
+	}
+	if !job.Resumable() {
+		return nil, nil, nil, usageError("job %s is %s and cannot be resumed", id, job.Status)
+	}
+
+	var groups []TermGroup
+	index := make(map[string]int)
+	inputs := make(map[string]string)
+	for _, term := range job.Terms {
+		if !term.Remaining() {
+			continue
+		}
+		if term.Input != "" {
+			inputs[term.Term] = term.Input
+		}
+		key := strings.Join(term.Types, ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, TermGroup{Types: term.Types})
+		}
+		groups[i].Terms = append(groups[i].Terms, term.Term)
+	}
+	return job, chunkGroups(groups, size), inputs, nil
+}
+
+// runStopper stops the workers of a parallel run after a failure every later
+// request would repeat, keeping the first such failure. Callers serialize
+// their calls.
+type runStopper struct {
+	ctx  context.Context
+	stop context.CancelFunc
+	err  error
+}
+
+// newRunStopper returns a stopper whose context the workers run with
+func newRunStopper(ctx context.Context) *runStopper {
+	runCtx, stop := context.WithCancel(ctx)
+	return &runStopper{ctx: runCtx, stop: stop}
+}
+
+// stopsRun reports whether err would fail every later request too, such as
+// an invalid API key or running out of credits
+func stopsRun(err error) bool {
+	return errors.Is(err, keyscore.ErrInsufficientCredits) || errors.Is(err, keyscore.ErrUnauthorized)
+}
+
+// cutShort reports whether err only means that the request was cancelled
+// because the run stopped. Such requests stay pending.
+func (s *runStopper) cutShort(err error) bool {
+	return err != nil && s.ctx.Err() != nil && errors.Is(err, context.Canceled)
+}
+
+// check stops the run when err is a failure every later request would repeat
+func (s *runStopper) check(err error) {
+	if stopsRun(err) {
+		s.fail(err)
+	}
+}
+
+// fail stops the run, reporting err unless an earlier failure stopped it
+func (s *runStopper) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	s.stop()
+}
+
+// batchRun sends every prepared group of a batch job, checkpointing the job
+// after each request, and reports the outcome of each term: a summary on
+// stdout and one result file per term
+type batchRun struct {
+	store     *jobs.Store
+	job       *jobs.Job
+	groups    []*preparedGroup
+	parallel  int
+	formatter Formatter
+	quiet     bool
+
+	// send runs the request of the group at index i and returns its rows,
+	// each tagged with the term that produced it
+	send func(ctx context.Context, i int) ([]map[string]interface{}, error)
+	// results counts what a term's rows stand for: one per row for searches,
+	// the sum of the counts for counts
+	results func(records []map[string]interface{}) int64
+
+	index     map[string]int // Position of each term in job.Terms
+	unmatched int            // Rows of this run not traced to a single term
+}
+
+func (b *batchRun) run(ctx context.Context) error {
+	b.index = make(map[string]int, len(b.job.Terms))
+	for i, term := range b.job.Terms {
+		b.index[term.Term] = i
+	}
+
+	if err := os.MkdirAll(b.job.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create results directory: %v", err)
+	}
+	b.job.Status = jobs.StatusRunning
+	if err := b.store.Save(b.job); err != nil {
+		return err
+	}
+	if !b.quiet {
+		fmt.Fprintf(os.Stderr, "Job %s: %d terms to run\n", b.job.ID, b.job.Remaining())
+	}
+
+	stopper := newRunStopper(ctx)
+	defer stopper.stop()
+	var mu sync.Mutex
+	var done int
+
+	errs := batch.Run(stopper.ctx, len(b.groups), b.parallel, func(ctx context.Context, i int) error {
+		rows, err := b.send(ctx, i)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if stopper.cutShort(err) {
+			return err
+		}
+		b.complete(i, rows, err)
+		stopper.check(err)
+		if saveErr := b.store.Save(b.job); saveErr != nil {
+			// A cancelled job is reported as such whatever stopped it first
+			if errors.Is(saveErr, jobs.ErrCancelled) {
+				stopper.err = saveErr
+			}
+			stopper.fail(saveErr)
+		}
+
+		done++
+		if !b.quiet {
+			fmt.Fprintf(os.Stderr, "\rCompleted %d of %d requests", done, len(b.groups))
+		}
+		return err
+	})
+	if !b.quiet && len(b.groups) > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	runErr := stopper.err
+	if runErr == nil {
+		runErr = ctx.Err()
+	}
+	for _, err := range errs {
+		if runErr == nil && err != nil {
+			runErr = err
+		}
+	}
+
+	switch {
+	case errors.Is(runErr, jobs.ErrCancelled) || b.job.Status == jobs.StatusCancelled:
+		b.job.Status = jobs.StatusCancelled
+	case b.job.Remaining() == 0:
+		b.job.Status = jobs.StatusCompleted
+	default:
+		b.job.Status = jobs.StatusInterrupted
+	}
+	b.job.Error = ""
+	if runErr != nil {
+		b.job.Error = runErr.Error()
+	}
+	if err := b.store.Save(b.job); err != nil && !errors.Is(err, jobs.ErrCancelled) {
+		return err
+	}
+
+	if err := b.report(); err != nil {
+		return err
+	}
+	if b.job.Status == jobs.StatusInterrupted && !b.quiet {
+		fmt.Fprintf(os.Stderr, "Job %s stopped with %d terms remaining; resume with: cliscore jobs resume %s\n", b.job.ID, b.job.Remaining(), b.job.ID)
+	}
+	return runErr
+}
+
+// complete records the outcome of the request of group i and writes the
+// result file of each of its terms
+func (b *batchRun) complete(i int, rows []map[string]interface{}, err error) {
+	group := b.groups[i]
+
+	byTerm := make(map[string][]map[string]interface{})
+	for _, row := range rows {
+		term, _ := row["term"].(string)
+		byTerm[term] = append(byTerm[term], row)
+	}
+
+	for _, term := range group.Terms {
+		entry := &b.job.Terms[b.index[term]]
+		termRows := byTerm[term]
+		delete(byTerm, term)
+
+		entry.Results, entry.Error = 0, ""
+		if len(termRows) > 0 {
+			entry.Results = b.results(termRows)
+		}
+		switch {
+		case err != nil:
+			entry.Status = jobs.TermFailed
+			entry.Error = err.Error()
+		case len(termRows) == 0:
+			entry.Status = jobs.TermNone
+		default:
+			entry.Status = jobs.TermFound
+		}
+
+		// Failed requests may still have returned some pages
+		if err != nil && len(termRows) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("%04d_%s.json", b.index[term]+1, config.SafeFilename(term))
+		if writeErr := writeJSONFile(filepath.Join(b.job.Dir, name), map[string]interface{}{
+			"term":    term,
+			"input":   entry.Input,
+			"types":   group.Types,
+			"results": termRows,
+		}); writeErr != nil {
+			entry.Status = jobs.TermFailed
+			entry.Error = writeErr.Error()
+			continue
+		}
+		entry.File = name
+	}
+
+	// Rows that could not be traced to a single term of their request
+	var unmatched []map[string]interface{}
+	for _, termRows := range byTerm {
+		unmatched = append(unmatched, termRows...)
+	}
+	if len(unmatched) > 0 {
+		b.unmatched += len(unmatched)
+		appendUnmatched(filepath.Join(b.job.Dir, "unmatched.json"), unmatched)
+	}
+}
+
+// appendUnmatched adds rows to the unmatched results file of a batch
+func appendUnmatched(path string, rows []map[string]interface{}) {
+	var existing []map[string]interface{}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &existing)
+	}
+	writeJSONFile(path, append(existing, rows...))
+}
+
+// report writes the summary of every term of the job, including those of
+// earlier runs, to stdout as a table or in the chosen format, followed by
+// the totals on stderr
+func (b *batchRun) report() error {
+	summary := b.job.Terms
+	if err := writeJSONFile(filepath.Join(b.job.Dir, "summary.json"), summary); err != nil {
+		return err
+	}
+
+	rows := make([]map[string]interface{}, len(summary))
+	var total int64
+	for i, entry := range summary {
+		row, err := termRecord(entry)
+		if err != nil {
+			return err
+		}
+		rows[i] = row
+		total += entry.Results
+	}
+
+	if b.quiet && b.formatter == nil {
+		fmt.Printf("%d\n", total)
+		return nil
+	}
+
+	formatter := b.formatter
+	if formatter == nil {
+		formatter = formatters["table"]
+	}
+	if err := writeOutput(os.Stdout, formatter, Document{Value: summary, Records: rows, Columns: batchColumns}); err != nil {
+		return err
+	}
+
+	if !b.quiet {
+		counts := b.job.Counts()
+		fmt.Fprintf(os.Stderr, "Processed %d terms in %d requests: %d with results, %d without, %d failed, %d skipped, %d pending\n",
+			len(summary), len(b.groups), counts[jobs.TermFound], counts[jobs.TermNone], counts[jobs.TermFailed], counts[jobs.TermSkipped], counts[jobs.TermPending])
+		if b.unmatched > 0 {
+			fmt.Fprintf(os.Stderr, "%d results could not be matched to a single term; see unmatched.json\n", b.unmatched)
+		}
+		fmt.Fprintf(os.Stderr, "Result files written to: %s\n", b.job.Dir)
+	}
+	return nil
+}
+
+// termRecord converts the state of a job term into a summary row
+func termRecord(term jobs.Term) (map[string]interface{}, error) {
+	row, err := valueRecord(term)
+	if err != nil {
+		return nil, err
+	}
+	row["types"] = strings.Join(term.Types, ", ")
+	return row, nil
+}
+
+// batchDir returns a new directory for the files of a batch
+func batchDir(resultsDir, command string) string {
+	return filepath.Join(resultsDir, fmt.Sprintf("%s-batch-%s", command, time.Now().Format("20060102-150405")))
+}
+
+func writeJSONFile(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write results file: %v", err)
+	}
+	return nil
+}
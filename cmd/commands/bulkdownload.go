@@ -0,0 +1,275 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"cliscore/internal/batch"
+	"cliscore/internal/spinner"
+	"cliscore/pkg/keyscore"
+)
+
+// Bulk download statuses
+const (
+	downloadDownloaded = "downloaded"
+	downloadSkipped    = "skipped"
+	downloadFailed     = "failed"
+	downloadPending    = "pending"
+)
+
+// manifestName is the file listing the outcome of a bulk download
+const manifestName = "manifest.json"
+
+// uuidPattern finds log UUIDs anywhere in a line, so plain lists, CSV files
+// and the NDJSON output of a search can all be read
+var uuidPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+
+// downloadColumns lead every row of a bulk download summary
+var downloadColumns = []string{"uuid", "status", "size", "attempts", "path", "error"}
+
+// downloadEntry is the outcome of one log in a bulk download
+type downloadEntry struct {
+	UUID     string `json:"uuid"`
+	Status   string `json:"status"`
+	Path     string `json:"path,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	Verified bool   `json:"verified,omitempty"`
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// downloadManifest is written to the output directory after a bulk download
+type downloadManifest struct {
+	Created    time.Time       `json:"created"`
+	Dir        string          `json:"dir"`
+	Total      int             `json:"total"`
+	Downloaded int             `json:"downloaded"`
+	Skipped    int             `json:"skipped"`
+	Failed     int             `json:"failed"`
+	Pending    int             `json:"pending"`
+	Downloads  []downloadEntry `json:"downloads"`
+}
+
+// readUUIDs returns the UUIDs found in args and in the lines of input,
+// without duplicates
+func readUUIDs(input string, args []string) ([]string, error) {
+	lines := args
+	if input != "" {
+		read, err := batch.ReadFile(input, batch.Options{Format: batch.FormatLines})
+		if err != nil {
+			return nil, err
+		}
+		lines = append(append([]string{}, args...), read...)
+	}
+
+	var uuids []string
+	for _, line := range lines {
+		for _, uuid := range uuidPattern.FindAllString(line, -1) {
+			uuids = append(uuids, strings.ToLower(uuid))
+		}
+	}
+	return batch.Dedupe(uuids), nil
+}
+
+// bulkDownload downloads many logs into one directory per UUID
+type bulkDownload struct {
+	client       *keyscore.Client
+	dir          string
+	file         string
+	parallel     int
+	retries      int
+	skipExisting bool
+	quiet        bool
+	formatter    Formatter
+}
+
+// run downloads every UUID, writes the manifest and prints a summary. It
+// returns the manifest once written, and fails when any download did.
+func (d *bulkDownload) run(ctx context.Context, uuids []string) (*downloadManifest, error) {
+	dir, err := filepath.Abs(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving output directory: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %v", err)
+	}
+	d.dir = dir
+
+	entries := make([]downloadEntry, len(uuids))
+	for i, uuid := range uuids {
+		entries[i] = downloadEntry{UUID: uuid, Status: downloadPending}
+	}
+
+	stopper := newRunStopper(ctx)
+	defer stopper.stop()
+	var mu sync.Mutex
+	var done int
+
+	batch.Run(stopper.ctx, len(uuids), d.parallel, func(ctx context.Context, i int) error {
+		entry, err := d.download(ctx, uuids[i])
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if stopper.cutShort(err) {
+			return err
+		}
+		entries[i] = entry
+		stopper.check(err)
+
+		done++
+		if !d.quiet {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", done, len(uuids), describeDownload(entry))
+		}
+		return err
+	})
+
+	manifest := downloadManifest{Created: time.Now(), Dir: d.dir, Total: len(entries), Downloads: entries}
+	for _, entry := range entries {
+		switch entry.Status {
+		case downloadDownloaded:
+			manifest.Downloaded++
+		case downloadSkipped:
+			manifest.Skipped++
+		case downloadFailed:
+			manifest.Failed++
+		default:
+			manifest.Pending++
+		}
+	}
+	manifestPath := filepath.Join(d.dir, manifestName)
+	if err := writeJSONFile(manifestPath, manifest); err != nil {
+		return nil, err
+	}
+
+	if err := d.report(manifest); err != nil {
+		return &manifest, err
+	}
+	if !d.quiet {
+		fmt.Fprintf(os.Stderr, "Downloaded %d, skipped %d, failed %d, pending %d; manifest written to: %s\n",
+			manifest.Downloaded, manifest.Skipped, manifest.Failed, manifest.Pending, manifestPath)
+	}
+
+	if stopper.err != nil {
+		return &manifest, stopper.err
+	}
+	if err := ctx.Err(); err != nil {
+		return &manifest, err
+	}
+	if manifest.Failed > 0 {
+		return &manifest, fmt.Errorf("%d of %d downloads failed; see %s", manifest.Failed, manifest.Total, manifestPath)
+	}
+	return &manifest, nil
+}
+
+// download fetches one log into its own directory, retrying failures that
+// may pass on another attempt. The client should not retry on its own, so
+// that Attempts counts every request.
+func (d *bulkDownload) download(ctx context.Context, uuid string) (downloadEntry, error) {
+	name := uuid + ".zip"
+	if d.file != "" {
+		name = filepath.Base(d.file)
+	}
+	path := filepath.Join(d.dir, uuid, name)
+	entry := downloadEntry{UUID: uuid, Path: path}
+
+	if d.skipExisting {
+		if sum, err := keyscore.VerifyDownload(path); err == nil {
+			if info, err := os.Stat(path); err == nil {
+				entry.Status = downloadSkipped
+				entry.Size = info.Size()
+				entry.SHA256 = sum
+				return entry, nil
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		entry.Status = downloadFailed
+		entry.Error = fmt.Sprintf("failed to create directory: %v", err)
+		return entry, errors.New(entry.Error)
+	}
+
+	for {
+		entry.Attempts++
+		result, err := d.client.Download(ctx, keyscore.DownloadOptions{UUID: uuid, File: d.file, OutputPath: path})
+		if err == nil {
+			entry.Status = downloadDownloaded
+			entry.Size = result.Size
+			entry.SHA256 = result.SHA256
+			entry.Verified = result.Verified
+			entry.Error = ""
+			return entry, nil
+		}
+
+		entry.Status = downloadFailed
+		entry.Error = err.Error()
+		if entry.Attempts > d.retries || !retryableDownload(err) {
+			return entry, err
+		}
+		select {
+		case <-ctx.Done():
+			return entry, ctx.Err()
+		case <-time.After(time.Duration(entry.Attempts) * time.Second):
+		}
+	}
+}
+
+// retryableDownload reports whether another attempt at a failed download
+// may succeed
+func retryableDownload(err error) bool {
+	return !errors.Is(err, keyscore.ErrNotFound) &&
+		!stopsRun(err) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// describeDownload returns the progress line of a finished download
+func describeDownload(entry downloadEntry) string {
+	switch entry.Status {
+	case downloadDownloaded:
+		return fmt.Sprintf("Downloaded %s (%s)", entry.UUID, spinner.FormatBytes(entry.Size))
+	case downloadSkipped:
+		return fmt.Sprintf("Skipped %s: already downloaded and verified", entry.UUID)
+	}
+	return fmt.Sprintf("Failed %s: %s", entry.UUID, entry.Error)
+}
+
+// report prints one row per UUID, with readable sizes unless a format is
+// chosen
+func (d *bulkDownload) report(manifest downloadManifest) error {
+	if d.formatter == nil && d.quiet {
+		return nil
+	}
+
+	records := make([]map[string]interface{}, len(manifest.Downloads))
+	for i, entry := range manifest.Downloads {
+		records[i] = map[string]interface{}{
+			"uuid":     entry.UUID,
+			"status":   entry.Status,
+			"size":     entry.Size,
+			"attempts": entry.Attempts,
+			"path":     entry.Path,
+			"error":    entry.Error,
+			"sha256":   entry.SHA256,
+			"verified": entry.Verified,
+		}
+		if d.formatter == nil {
+			records[i]["size"] = spinner.FormatBytes(entry.Size)
+		}
+	}
+
+	formatter := d.formatter
+	if formatter == nil {
+		formatter = formatters["table"]
+	}
+	return writeOutput(os.Stdout, formatter, Document{Value: manifest, Records: records, Columns: downloadColumns})
+}
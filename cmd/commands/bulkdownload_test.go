@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"cliscore/pkg/keyscore"
+)
+
+const (
+	foundUUID   = "ce1869f2-b922-456b-882c-58aa4ad5f266"
+	missingUUID = "0b0e4c1e-58a2-4d4f-9a0e-3c1f1f6f0d2a"
+)
+
+func TestReadUUIDs(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "uuids.ndjson")
+	lines := `{"uuid":"` + strings.ToUpper(foundUUID) + `","email":"admin@example.com"}` + "\n" +
+		`{"uuid":"` + foundUUID + `"}` + "\n" + "no uuid here\n"
+	if err := os.WriteFile(input, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	uuids, err := readUUIDs(input, []string{missingUUID})
+	if err != nil {
+		t.Fatalf("readUUIDs: %v", err)
+	}
+	if expected := []string{missingUUID, foundUUID}; !reflect.DeepEqual(uuids, expected) {
+		t.Errorf("readUUIDs = %v, expected %v", uuids, expected)
+	}
+}
+
+func TestBulkDownload(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Query().Get("uuid") != foundUUID {
+			http.Error(w, `{"error":"log not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("PK archive bytes"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	d := bulkDownload{
+		client:       keyscore.New(keyscore.WithBaseURL(srv.URL), keyscore.WithAPIKey("test-key")),
+		dir:          dir,
+		parallel:     2,
+		retries:      2,
+		skipExisting: true,
+		quiet:        true,
+	}
+
+	statuses := func() map[string]string {
+		data, err := os.ReadFile(filepath.Join(dir, manifestName))
+		if err != nil {
+			t.Fatalf("reading manifest: %v", err)
+		}
+		var manifest downloadManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			t.Fatalf("decoding manifest: %v", err)
+		}
+		statuses := make(map[string]string)
+		for _, entry := range manifest.Downloads {
+			statuses[entry.UUID] = entry.Status
+		}
+		return statuses
+	}
+
+	first := d
+	if _, err := first.run(context.Background(), []string{foundUUID, missingUUID}); err == nil || !strings.Contains(err.Error(), "1 of 2 downloads failed") {
+		t.Errorf("run error = %v, expected the failed download to be reported", err)
+	}
+	if expected := map[string]string{foundUUID: downloadDownloaded, missingUUID: downloadFailed}; !reflect.DeepEqual(statuses(), expected) {
+		t.Errorf("manifest = %v, expected %v", statuses(), expected)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, foundUUID, foundUUID+".zip")); err != nil || string(data) != "PK archive bytes" {
+		t.Errorf("downloaded file = %q, %v", data, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("first run sent %d requests, expected 2 without retrying the missing log", got)
+	}
+
+	second := d
+	second.run(context.Background(), []string{foundUUID, missingUUID})
+	if expected := map[string]string{foundUUID: downloadSkipped, missingUUID: downloadFailed}; !reflect.DeepEqual(statuses(), expected) {
+		t.Errorf("manifest after rerun = %v, expected %v", statuses(), expected)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("sent %d requests in total, expected the verified log to be skipped", got)
+	}
+}
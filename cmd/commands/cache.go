@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"cliscore/internal/cache"
+	"cliscore/internal/config"
+	"cliscore/internal/spinner"
+	"cliscore/pkg/keyscore"
+)
+
+type CacheCommand struct{}
+
+func (c *CacheCommand) Name() string {
+	return "cache"
+}
+
+func (c *CacheCommand) Description() string {
+	return "Show or clear the search and count response cache (stats|clear)"
+}
+
+func (c *CacheCommand) Execute(args []string) error {
+	action := "stats"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	flagSet := flag.NewFlagSet("cache", flag.ContinueOnError)
+	output := outputFlag(flagSet)
+
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+
+	cfg := config.Load()
+	responses, err := openCache(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "stats":
+		_, formatter, err := resolveOutput(*output, cfg.Output)
+		if err != nil {
+			return err
+		}
+		stats, err := responses.Stats()
+		if err != nil {
+			return err
+		}
+		if formatter != nil {
+			return writeOutput(os.Stdout, formatter, Document{Value: stats})
+		}
+
+		fmt.Printf("Cache directory: %s\n", stats.Dir)
+		if !cfg.CacheEnabled() {
+			fmt.Println("Caching is disabled (cacheTTL is -1)")
+		}
+		fmt.Printf("Entries: %d (%d expired)\n", stats.Entries, stats.Expired)
+		if stats.MaxSize > 0 {
+			fmt.Printf("Size: %s of %s\n", spinner.FormatBytes(stats.Size), spinner.FormatBytes(stats.MaxSize))
+		} else {
+			fmt.Printf("Size: %s (no limit)\n", spinner.FormatBytes(stats.Size))
+		}
+		fmt.Printf("TTL: %s\n", stats.TTL)
+		if stats.Entries > 0 {
+			fmt.Printf("Oldest entry: %s\n", stats.Oldest.Format(time.DateTime))
+			fmt.Printf("Newest entry: %s\n", stats.Newest.Format(time.DateTime))
+		}
+		return nil
+	case "clear":
+		n, err := responses.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d cached responses\n", n)
+		return nil
+	}
+	return usageError("unknown cache action %q (available: stats, clear)", action)
+}
+
+// openCache returns the response cache configured by cfg
+func openCache(cfg *config.Config) (*cache.Cache, error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(dir, cfg.CacheTTLDuration(), cfg.CacheMaxBytes()), nil
+}
+
+// addCacheFlags registers -no-cache and -refresh on flagSet
+func addCacheFlags(flagSet *flag.FlagSet) (noCache, refresh *bool) {
+	noCache = flagSet.Bool("no-cache", false, "Neither read nor write the response cache")
+	refresh = flagSet.Bool("refresh", false, "Fetch fresh results and update the response cache")
+	return noCache, refresh
+}
+
+// responseCache serves search and count responses from the on-disk cache
+// and counts the hits so they can be reported
+type responseCache struct {
+	cache   *cache.Cache // nil when caching is off
+	refresh bool         // Skip lookups but still store responses
+	scope   string       // Keeps accounts and API servers apart
+
+	mu     sync.Mutex
+	hits   int
+	oldest time.Time
+}
+
+// newResponseCache returns the cache for one command run. Errors opening
+// the cache only disable it.
+func newResponseCache(cfg *config.Config, noCache, refresh bool) *responseCache {
+	r := &responseCache{refresh: refresh}
+	if noCache || !cfg.CacheEnabled() {
+		return r
+	}
+	if c, err := openCache(cfg); err == nil {
+		r.cache = c
+	}
+	sum := sha256.Sum256([]byte(cfg.APIKey))
+	r.scope = cfg.BaseURL + " " + hex.EncodeToString(sum[:8])
+	return r
+}
+
+// search runs a search like runSearch, unless an identical one is cached
+func (r *responseCache) search(ctx context.Context, client *keyscore.Client, req *keyscore.SearchRequest, pagination *keyscore.SearchPaginationParams, all bool, opts keyscore.SearchAllOptions) (*keyscore.SearchResponse, error) {
+	key := map[string]interface{}{
+		"kind":    "search",
+		"request": canonicalRequest(req.Terms, req.Types, req.Wildcard, req.Source, req.Operator),
+	}
+	if pagination != nil {
+		key["pagination"] = map[string]interface{}{"page": pagination.Page, "pages": pagination.Pages, "pageSize": pagination.PageSize}
+	}
+	if all {
+		key["all"] = map[string]interface{}{"pageSize": opts.PageSize, "maxResults": opts.MaxResults, "maxCredits": opts.MaxCredits}
+	}
+
+	var response *keyscore.SearchResponse
+	if r.lookup(key, &response) {
+		return response, nil
+	}
+	response, err := runSearch(ctx, client, req, pagination, all, opts)
+	if err == nil {
+		r.store(key, response)
+	}
+	return response, err
+}
+
+// count runs a count, unless an identical one is cached
+func (r *responseCache) count(ctx context.Context, client *keyscore.Client, req *keyscore.CountRequest) (*keyscore.DetailedCountResponse, error) {
+	key := map[string]interface{}{
+		"kind":    "count",
+		"request": canonicalRequest(req.Terms, req.Types, req.Wildcard, req.Source, req.Operator),
+	}
+
+	var response *keyscore.DetailedCountResponse
+	if r.lookup(key, &response) {
+		return response, nil
+	}
+	response, err := client.Count(ctx, req)
+	if err == nil {
+		r.store(key, response)
+	}
+	return response, err
+}
+
+// canonicalRequest describes a request independently of the order of its
+// terms and types
+func canonicalRequest(terms, types []string, wildcard bool, source string, operator *string) map[string]interface{} {
+	terms = append([]string{}, terms...)
+	types = append([]string{}, types...)
+	sort.Strings(terms)
+	sort.Strings(types)
+	return map[string]interface{}{
+		"terms":    terms,
+		"types":    types,
+		"wildcard": wildcard,
+		"source":   source,
+		"operator": operator,
+	}
+}
+
+func (r *responseCache) lookup(key map[string]interface{}, out interface{}) bool {
+	if r.cache == nil || r.refresh {
+		return false
+	}
+	key["scope"] = r.scope
+	hash, err := cache.Key(key)
+	if err != nil {
+		return false
+	}
+	created, ok := r.cache.Get(hash, out)
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hits++
+	if r.oldest.IsZero() || created.Before(r.oldest) {
+		r.oldest = created
+	}
+	return true
+}
+
+// store caches a response. Failing to cache never fails the command.
+func (r *responseCache) store(key map[string]interface{}, value interface{}) {
+	if r.cache == nil {
+		return
+	}
+	key["scope"] = r.scope
+	if hash, err := cache.Key(key); err == nil {
+		r.cache.Put(hash, value)
+	}
+}
+
+// report tells on stderr that results came from the cache
+func (r *responseCache) report(quiet bool) {
+	if quiet || r.hits == 0 {
+		return
+	}
+	age := time.Since(r.oldest).Round(time.Second)
+	if r.hits == 1 {
+		fmt.Fprintf(os.Stderr, "Served from cache (fetched %s ago); pass -refresh to fetch again\n", age)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Served %d responses from cache (oldest fetched %s ago); pass -refresh to fetch again\n", r.hits, age)
+}
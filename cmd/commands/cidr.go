@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"cliscore/internal/detector"
+)
+
+// CIDR range handling selected with -cidr
+const (
+	cidrAuto     = "auto"     // Wildcards for IPv4, expansion for small IPv6 ranges
+	cidrExpand   = "expand"   // One term per address
+	cidrWildcard = "wildcard" // Wildcard terms such as 10.1.* (IPv4 only)
+	cidrNone     = "none"     // Send the range unchanged
+)
+
+// maxCIDRTerms caps how many terms a single CIDR range may turn into
+const maxCIDRTerms = 256
+
+// prepareIPTerms puts IP addresses in canonical form and converts CIDR
+// ranges into terms the API can search for. It reports whether any of the
+// resulting terms are wildcards, which requires a wildcard search.
+func prepareIPTerms(terms []string, mode string, quiet bool) ([]string, bool, error) {
+	switch mode {
+	case cidrAuto, cidrExpand, cidrWildcard, cidrNone:
+	default:
+		return nil, false, usageError("unknown -cidr mode %q (available: auto, expand, wildcard, none)", mode)
+	}
+
+	var prepared []string
+	wildcard := false
+	for _, term := range terms {
+		if addr, ok := detector.ParseIP(term); ok {
+			prepared = append(prepared, addr.String())
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(term)
+		if err != nil || mode == cidrNone {
+			prepared = append(prepared, term)
+			continue
+		}
+
+		expanded, err := expandCIDR(prefix, mode)
+		if err != nil {
+			return nil, false, usageError("%v; narrow the range or use -cidr none", err)
+		}
+		for _, t := range expanded {
+			if strings.Contains(t, "*") {
+				wildcard = true
+			}
+		}
+		if !quiet && (len(expanded) != 1 || expanded[0] != term) {
+			fmt.Fprintf(os.Stderr, "Searching %s as %d term(s)\n", term, len(expanded))
+		}
+		prepared = append(prepared, expanded...)
+	}
+
+	return prepared, wildcard, nil
+}
+
+func expandCIDR(prefix netip.Prefix, mode string) ([]string, error) {
+	cidr := prefix.String()
+	switch {
+	case mode == cidrExpand:
+		return detector.ExpandCIDR(cidr, maxCIDRTerms)
+	case mode == cidrWildcard || prefix.Addr().Is4():
+		return detector.CIDRWildcards(cidr, maxCIDRTerms)
+	default:
+		return detector.ExpandCIDR(cidr, maxCIDRTerms)
+	}
+}
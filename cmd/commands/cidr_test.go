@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrepareIPTerms(t *testing.T) {
+	tests := []struct {
+		terms    []string
+		mode     string
+		expected []string
+		wildcard bool
+	}{
+		{[]string{"admin@example.com", "[2001:DB8::1]:443"}, cidrAuto, []string{"admin@example.com", "2001:db8::1"}, false},
+		{[]string{"10.1.0.0/16"}, cidrAuto, []string{"10.1.*"}, true},
+		{[]string{"10.1.0.0/30"}, cidrExpand, []string{"10.1.0.0", "10.1.0.1", "10.1.0.2", "10.1.0.3"}, false},
+		{[]string{"2001:db8::/127"}, cidrAuto, []string{"2001:db8::", "2001:db8::1"}, false},
+		{[]string{"10.1.0.0/16"}, cidrNone, []string{"10.1.0.0/16"}, false},
+	}
+
+	for _, test := range tests {
+		terms, wildcard, err := prepareIPTerms(test.terms, test.mode, true)
+		if err != nil {
+			t.Errorf("prepareIPTerms(%v, %s) returned error: %v", test.terms, test.mode, err)
+			continue
+		}
+		if !reflect.DeepEqual(terms, test.expected) || wildcard != test.wildcard {
+			t.Errorf("prepareIPTerms(%v, %s) = %v, %v, expected %v, %v", test.terms, test.mode, terms, wildcard, test.expected, test.wildcard)
+		}
+	}
+
+	for _, terms := range [][]string{{"2001:db8::/32"}, {"10.0.0.0/8"}} {
+		if _, _, err := prepareIPTerms(terms, cidrExpand, true); err == nil {
+			t.Errorf("prepareIPTerms(%v, expand) succeeded, expected the size limit to apply", terms)
+		}
+	}
+	if _, _, err := prepareIPTerms([]string{"10.0.0.1"}, "bogus", true); err == nil {
+		t.Error("prepareIPTerms accepted an unknown mode")
+	}
+}
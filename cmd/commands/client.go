@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"cliscore/internal/config"
+	"cliscore/pkg/keyscore"
+)
+
+// newClient builds a keyscore API client from the CLI configuration
+func newClient(cfg *config.Config) *keyscore.Client {
+	retry := keyscore.DefaultRetryPolicy
+	retry.MaxRetries = cfg.Retries()
+
+	return keyscore.New(
+		keyscore.WithBaseURL(cfg.BaseURL),
+		keyscore.WithAPIKey(cfg.APIKey),
+		keyscore.WithUserAgent("cliscore/"+keyscore.Version),
+		keyscore.WithRequestTimeout(cfg.RequestTimeoutDuration()),
+		keyscore.WithRetryPolicy(retry),
+		keyscore.WithRateLimit(cfg.RateLimit, cfg.RateBurst),
+	)
+}
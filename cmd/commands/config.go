@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"cliscore/internal/config"
 )
@@ -18,6 +20,12 @@ func (c *ConfigCommand) Description() string {
 }
 
 func (c *ConfigCommand) Execute(args []string) error {
+	// No options, but -h still shows help instead of running the command
+	flagSet := flag.NewFlagSet("config", flag.ContinueOnError)
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+
 	cfg := config.Load()
 	
 	fmt.Println("Current configuration:")
@@ -36,6 +44,11 @@ func (c *ConfigCommand) Execute(args []string) error {
 	
 	fmt.Printf("Spinner style: %s\n", cfg.SpinnerStyle)
 
+	if cfg.Output != "" {
+		fmt.Printf("Output format: %s\n", cfg.Output)
+	}
+	fmt.Printf("Phone country code: +%s\n", strings.TrimPrefix(cfg.CountryCode, "+"))
+
 	if config.ConfigFileExists() {
 		homeDir, _ := os.UserHomeDir()
 		configPath := fmt.Sprintf("%s/.keyscore-cli/config.json", homeDir)
@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"cliscore/internal/config"
+)
+
+// commandContext returns a context that is cancelled on SIGINT or SIGTERM
+// and, when configured, once the global command timeout elapses.
+func commandContext(cfg *config.Config) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout := cfg.TimeoutDuration(); timeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		return timeoutCtx, func() {
+			cancel()
+			stop()
+		}
+	}
+	return ctx, stop
+}
@@ -1,16 +1,16 @@
 package commands
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
-	"cliscore/internal/client"
 	"cliscore/internal/config"
-	"cliscore/internal/detector"
-	"cliscore/internal/models"
 	"cliscore/internal/spinner"
+	"cliscore/pkg/keyscore"
 )
 
 type CountCommand struct{}
@@ -25,87 +25,90 @@ func (c *CountCommand) Description() string {
 
 func (c *CountCommand) Execute(args []string) error {
 	var (
-		terms        []string
-		types        []string
-		wildcard     bool
-		source       string
-		apiKey       string
-		saveResults  bool
-		noSave       bool
-		resultsDir   string
-		showSpinner  bool
-		quiet        bool
-		operator     string
+		wildcard    bool
+		source      string
+		showSpinner bool
+		operator    string
 	)
 
-	flagSet := flag.NewFlagSet("count", flag.ExitOnError)
+	flagSet := flag.NewFlagSet("count", flag.ContinueOnError)
 	flagSet.StringVar(&source, "source", "xkeyscore", "Source to count from")
 	flagSet.BoolVar(&wildcard, "wildcard", false, "Enable wildcard search")
-	flagSet.StringVar(&apiKey, "api-key", "", "API key for authentication (overrides env var)")
-	flagSet.BoolVar(&saveResults, "save", false, "Save results to file")
-	flagSet.BoolVar(&noSave, "no-save", false, "Don't save results to file")
-	flagSet.StringVar(&resultsDir, "results-dir", "", "Results directory (overrides config)")
+	common := addCommonFlags(flagSet)
 	flagSet.BoolVar(&showSpinner, "spinner", true, "Show loading spinner")
-	flagSet.BoolVar(&quiet, "quiet", false, "Quiet mode (no spinner)")
 	flagSet.StringVar(&operator, "operator", "", "Search operator (AND, LOGS)")
+	termOpts := addTermFlags(flagSet)
+	output := outputFlag(flagSet)
+	noCache, refresh := addCacheFlags(flagSet)
+	// Counts cannot be split by term, so every term is counted on its own
+	batchOpts := addBatchFlags(flagSet, false)
 
-	flagSet.Parse(args)
-
-	terms = flagSet.Args()
-
-	if len(terms) < 1 {
-		fmt.Println("Usage: cliscore count [options] <terms...>")
-		flagSet.PrintDefaults()
-		os.Exit(1)
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
 	}
 
-	if len(types) == 0 {
-		types = DetectOrPromptTypes(terms, detector.New())
-	}
-
-	cfg := config.Load()
-	if apiKey != "" {
-		cfg.APIKey = apiKey
-	}
-	
-	// Override save settings with command line flags
-	if saveResults {
-		cfg.SaveResults = true
-	}
-	if noSave {
-		cfg.SaveResults = false
-	}
-	if resultsDir != "" {
-		cfg.ResultsDir = resultsDir
+	quiet := common.quiet
+	terms, err := readCommandTerms("count", flagSet, batchOpts)
+	if err != nil {
+		return err
 	}
 
-	apiClient := client.New(cfg)
+	cfg := common.loadConfig()
 
-	// Map types like frontend does (login -> email)
-	mappedTypes := make([]string, len(types))
-	for i, t := range types {
-		if t == "login" {
-			mappedTypes[i] = "email"
-		} else {
-			mappedTypes[i] = t
-		}
+	_, formatter, err := resolveOutput(*output, cfg.Output)
+	if err != nil {
+		return err
 	}
 
-	req := &models.CountRequest{
-		Terms:    terms,
-		Types:    mappedTypes,
-		Wildcard: wildcard,
-		Source:   source,
+	plan, err := termOpts.prepare("count", terms, cfg, batchOpts, quiet)
+	if err != nil {
+		return err
 	}
+	terms, types, prepared := plan.terms, plan.types, plan.prepared
 
-	// Set operator if provided
-	if operator != "" {
-		req.Operator = &operator
+	session := newAPISession(cfg, *noCache, *refresh, quiet)
+	defer session.close()
+	apiClient, cached, ctx := session.client, session.cache, session.ctx
+
+	if batchOpts.enabled() {
+		job, err := batchOpts.start("count", args, plan.groups, plan.skipped, plan.inputs, cfg.ResultsDir)
+		if err != nil {
+			return err
+		}
+		run := &batchRun{
+			store:     batchOpts.store,
+			job:       job,
+			groups:    prepared,
+			parallel:  batchOpts.parallel,
+			formatter: formatter,
+			quiet:     quiet,
+			send: func(ctx context.Context, i int) ([]map[string]interface{}, error) {
+				response, err := cached.count(ctx, apiClient, countRequest(prepared[i], source, wildcard, operator))
+				if err != nil {
+					return nil, err
+				}
+				records := countRecords(response)
+				for _, record := range records {
+					record["term"] = prepared[i].Terms[0]
+				}
+				return records, nil
+			},
+			results: func(records []map[string]interface{}) int64 {
+				var total int64
+				for _, record := range records {
+					if count, ok := record["count"].(float64); ok {
+						total += int64(count)
+					}
+				}
+				return total
+			},
+		}
+		return run.run(ctx)
 	}
 
 	// Start spinner if enabled
 	var spin *spinner.Spinner
-	if showSpinner && !quiet {
+	if showSpinner && !quiet && formatter == nil {
 		countMsg := fmt.Sprintf("Counting %s in %s...", strings.Join(terms, ", "), strings.Join(types, ", "))
 		spin = config.CreateSpinner(countMsg)
 		if spin != nil {
@@ -113,19 +116,41 @@ func (c *CountCommand) Execute(args []string) error {
 		}
 	}
 
-	response, err := apiClient.Count(req, cfg.APIKey)
+	// Each group of terms is counted only for its own types
+	responses := make([]*keyscore.DetailedCountResponse, len(prepared))
+	errs := make([]error, len(prepared))
+	forEachGroup(prepared, func(i int, group *preparedGroup) {
+		responses[i], errs[i] = cached.count(ctx, apiClient, countRequest(group, source, wildcard, operator))
+	})
 	
 	// Stop spinner
 	if spin != nil {
 		spin.Stop()
 	}
-	
+
+	if len(prepared) > 1 {
+		return printGroupCounts(prepared, responses, errs, formatter, cfg, common, terms, types)
+	}
+
+	response, err := responses[0], errs[0]
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	if !quiet {
+	countResult := map[string]interface{}{
+		"total_count": response.TotalCount,
+		"took":        response.Took,
+		"counts":      response.Counts,
+	}
+
+	notices := os.Stdout
+	if formatter != nil {
+		notices = os.Stderr
+		doc := Document{Value: countResult, Records: countRecords(response), Columns: []string{"type", "count"}}
+		if err := writeOutput(os.Stdout, formatter, doc); err != nil {
+			return err
+		}
+	} else if !quiet {
 		fmt.Printf("Count Results: %s\n", formatNumber(response.TotalCount))
 		if response.Took > 0 {
 			fmt.Printf("Time taken: %dms\n", response.Took)
@@ -142,16 +167,109 @@ func (c *CountCommand) Execute(args []string) error {
 	}
 
 	// Save results if enabled
+	common.saveResults(cfg, notices, countResult, "count", terms, types)
+
+	return nil
+}
+
+// countRequest builds the count request for one group of terms
+func countRequest(group *preparedGroup, source string, wildcard bool, operator string) *keyscore.CountRequest {
+	req := &keyscore.CountRequest{
+		Terms:    group.SearchTerms,
+		Types:    apiTypes(group.Types),
+		Wildcard: wildcard || group.Wildcard,
+		Source:   source,
+	}
+	if operator != "" {
+		req.Operator = &operator
+	}
+	return req
+}
+
+// countRecords returns one row per type, sorted by type name
+func countRecords(response *keyscore.DetailedCountResponse) []map[string]interface{} {
+	types := make([]string, 0, len(response.Counts))
+	for t := range response.Counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	records := make([]map[string]interface{}, len(types))
+	for i, t := range types {
+		records[i] = map[string]interface{}{"type": t, "count": response.Counts[t]}
+	}
+	return records
+}
+
+// printGroupCounts shows the counts of several term groups: their combined
+// total, then each group's counts
+func printGroupCounts(groups []*preparedGroup, responses []*keyscore.DetailedCountResponse, errs []error, formatter Formatter, cfg *config.Config, common *commonFlags, terms, types []string) error {
+	var partialErr error
+	var total, took int64
+	var records []map[string]interface{}
+	groupResults := make([]map[string]interface{}, 0, len(groups))
+
+	for i, group := range groups {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "Warning: count for %s failed: %v\n", group.label(), errs[i])
+			if partialErr == nil {
+				partialErr = errs[i]
+			}
+			continue
+		}
+		response := responses[i]
+		total += response.TotalCount
+		if response.Took > took {
+			took = response.Took
+		}
+		for _, record := range countRecords(response) {
+			record["term"] = strings.Join(group.Terms, ", ")
+			records = append(records, record)
+		}
+		groupResults = append(groupResults, map[string]interface{}{
+			"terms":       group.Terms,
+			"types":       group.Types,
+			"total_count": response.TotalCount,
+			"counts":      response.Counts,
+		})
+	}
+	if len(groupResults) == 0 {
+		return partialErr
+	}
+
 	countResult := map[string]interface{}{
-		"total_count": response.TotalCount,
-		"took":        response.Took,
-		"counts":      response.Counts,
+		"total_count": total,
+		"took":        took,
+		"groups":      groupResults,
 	}
-	if err := config.SaveResults(countResult, "count", terms, types); err != nil {
-		if !quiet {
-			fmt.Printf("Warning: Failed to save results: %v\n", err)
+
+	notices := os.Stdout
+	if formatter != nil {
+		notices = os.Stderr
+		doc := Document{Value: countResult, Records: records, Columns: []string{"term", "type", "count"}}
+		if err := writeOutput(os.Stdout, formatter, doc); err != nil {
+			return err
 		}
+	} else if !common.quiet {
+		fmt.Printf("Count Results: %s\n", formatNumber(total))
+		if took > 0 {
+			fmt.Printf("Time taken: %dms\n", took)
+		}
+		fmt.Printf("Detailed counts:\n")
+		for i, group := range groups {
+			if errs[i] != nil {
+				continue
+			}
+			fmt.Printf("  %s:\n", strings.Join(group.Terms, ", "))
+			for _, record := range countRecords(responses[i]) {
+				fmt.Printf("    %s: %s\n", record["type"], formatNumber(record["count"]))
+			}
+		}
+	} else {
+		fmt.Printf("%d\n", total)
 	}
 
-	return nil
-}
\ No newline at end of file
+	common.saveResults(cfg, notices, countResult, "count", terms, types)
+
+	return partialErr
+}
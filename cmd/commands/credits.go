@@ -4,9 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"os"
-
-	"cliscore/internal/client"
-	"cliscore/internal/config"
 )
 
 type CreditsCommand struct{}
@@ -20,38 +17,47 @@ func (c *CreditsCommand) Description() string {
 }
 
 func (c *CreditsCommand) Execute(args []string) error {
-	var (
-		apiKey string
-		quiet  bool
-	)
-
-	flagSet := flag.NewFlagSet("credits", flag.ExitOnError)
-	flagSet.StringVar(&apiKey, "api-key", "", "API key for authentication (overrides env var)")
-	flagSet.BoolVar(&quiet, "quiet", false, "Quiet mode (minimal output)")
+	flagSet := flag.NewFlagSet("credits", flag.ContinueOnError)
+	common := addCommonFlags(flagSet)
+	output := outputFlag(flagSet)
 
-	if err := flagSet.Parse(args); err != nil {
+	if err := parseFlags(flagSet, args); err != nil {
 		return err
 	}
 
-	cfg := config.Load()
-	if apiKey != "" {
-		cfg.APIKey = apiKey
+	cfg := common.loadConfig()
+
+	_, formatter, err := resolveOutput(*output, cfg.Output)
+	if err != nil {
+		return err
 	}
 
 	if cfg.APIKey == "" {
-		fmt.Println("Error: API key is required. Set CLISCORE_API_KEY environment variable or use --api-key flag")
-		os.Exit(1)
+		return usageError("API key is required. Set CLISCORE_API_KEY environment variable or use --api-key flag")
 	}
 
-	apiClient := client.New(cfg)
+	apiClient := newClient(cfg)
 
-	response, err := apiClient.GetCredits(cfg.APIKey)
+	ctx, cancel := commandContext(cfg)
+	defer cancel()
+
+	response, err := apiClient.GetCredits(ctx)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	if !quiet {
+	notices := os.Stdout
+	if formatter != nil {
+		notices = os.Stderr
+		record, err := valueRecord(response)
+		if err != nil {
+			return err
+		}
+		doc := Document{Value: response, Records: []map[string]interface{}{record}, Columns: []string{"credits"}}
+		if err := writeOutput(os.Stdout, formatter, doc); err != nil {
+			return err
+		}
+	} else if !common.quiet {
 		if response.Message != "" {
 			fmt.Printf("%s\n", response.Message)
 		}
@@ -60,5 +66,7 @@ func (c *CreditsCommand) Execute(args []string) error {
 		fmt.Printf("%d\n", response.Credits)
 	}
 
+	common.saveResults(cfg, notices, response, "credits", nil, nil)
+
 	return nil
 }
\ No newline at end of file
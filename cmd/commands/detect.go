@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"cliscore/internal/config"
+	"cliscore/internal/detector"
+)
+
+type DetectCommand struct{}
+
+func (c *DetectCommand) Name() string {
+	return "detect"
+}
+
+func (c *DetectCommand) Description() string {
+	return "Show how terms are classified, with confidence scores"
+}
+
+// detectColumns lead every row of the detect output
+var detectColumns = []string{"input", "term", "unicode", "type", "confidence", "rule", "detail"}
+
+func (c *DetectCommand) Execute(args []string) error {
+	var all bool
+
+	flagSet := flag.NewFlagSet("detect", flag.ContinueOnError)
+	flagSet.BoolVar(&all, "all", false, "Show every candidate type, not only the most likely")
+	output := outputFlag(flagSet)
+
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+
+	terms := flagSet.Args()
+	if len(terms) < 1 {
+		fmt.Println("Usage: cliscore detect [options] <terms...>")
+		flagSet.PrintDefaults()
+		return usageError("at least one term is required")
+	}
+
+	cfg := config.Load()
+	_, formatter, err := resolveOutput(*output, cfg.Output)
+	if err != nil {
+		return err
+	}
+
+	d, err := newDetector(cfg)
+	if err != nil {
+		return err
+	}
+
+	var records []map[string]interface{}
+	for _, term := range terms {
+		normalized := detector.Normalize(term)
+		candidates := d.Best(normalized.Term)
+		if all {
+			candidates = d.Classify(normalized.Term)
+		}
+		if len(candidates) == 0 {
+			candidates = []detector.Candidate{{}}
+		}
+		for _, candidate := range candidates {
+			records = append(records, candidateRecord(normalized, candidate))
+		}
+	}
+
+	if formatter == nil {
+		formatter = formatters["table"]
+	}
+	return writeOutput(os.Stdout, formatter, Document{Value: records, Records: records, Columns: detectColumns})
+}
+
+// candidateRecord converts a candidate into a row. Types are shown under
+// their search type, so "email" reads as "login". The input and Unicode
+// form are only included when they differ from the term.
+func candidateRecord(term detector.Normalized, candidate detector.Candidate) map[string]interface{} {
+	record := map[string]interface{}{"term": term.Term}
+	if term.Changed() {
+		record["input"] = term.Input
+	}
+	if term.Unicode != "" {
+		record["unicode"] = term.Unicode
+	}
+	if candidate.Type == "" {
+		return record
+	}
+
+	record["type"] = normalizeType(candidate.Type)
+	record["confidence"] = candidate.Confidence
+	record["rule"] = candidate.Rule
+	if candidate.Detail != "" {
+		record["detail"] = candidate.Detail
+	}
+	return record
+}
+
+// newDetector returns the built-in detector extended with the rules from
+// the config file
+func newDetector(cfg *config.Config) (*detector.Detector, error) {
+	d := detector.New()
+	for i, rule := range cfg.DetectionRules {
+		name := rule.Name
+		if name == "" {
+			name = "rule" + strconv.Itoa(i+1)
+		}
+		if !isKnownType(normalizeType(rule.Type)) {
+			return nil, fmt.Errorf("error in detection rule %q: unknown type %q", name, rule.Type)
+		}
+		confidence := rule.Confidence
+		if confidence == 0 {
+			confidence = config.DefaultRuleConfidence
+		}
+		regexRule, err := detector.NewRegexRule(name, rule.Type, rule.Pattern, confidence)
+		if err != nil {
+			return nil, fmt.Errorf("error in detection rules: %v", err)
+		}
+		d.Register(regexRule)
+	}
+	return d, nil
+}
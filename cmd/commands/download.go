@@ -4,10 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
-	"cliscore/internal/client"
-	"cliscore/internal/config"
+	"cliscore/internal/archive"
 	"cliscore/internal/spinner"
+	"cliscore/pkg/keyscore"
 )
 
 type DownloadCommand struct{}
@@ -25,67 +26,218 @@ func (c *DownloadCommand) Execute(args []string) error {
 		uuid       string
 		filePath   string
 		outputPath string
-		apiKey     string
-		quiet      bool
+		list       bool
+		extractDir string
+		maxExtract int64
+		input      string
+		outputDir  string
+		parallel   int
+		retries    int
+		skip       bool
 	)
 
-	flag.StringVar(&uuid, "uuid", "", "UUID of the log file")
-	flag.StringVar(&filePath, "file", "", "Specific file to extract from the archive")
-	flag.StringVar(&outputPath, "output", "", "Output file path")
-	flag.StringVar(&apiKey, "api-key", "", "API key for authentication (overrides env var)")
-	flag.BoolVar(&quiet, "quiet", false, "Quiet mode (minimal output)")
-
-	flag.Parse()
+	flagSet := flag.NewFlagSet("download", flag.ContinueOnError)
+	flagSet.StringVar(&uuid, "uuid", "", "UUID of the log file")
+	flagSet.StringVar(&filePath, "file", "", "Specific file to extract from the archive")
+	flagSet.StringVar(&outputPath, "output", "", "Output file path")
+	common := addCommonFlags(flagSet)
+	flagSet.BoolVar(&list, "list", false, "List the entries of the downloaded archive")
+	flagSet.StringVar(&extractDir, "extract", "", "Extract the downloaded archive into this directory, skipping unsafe entries")
+	flagSet.Int64Var(&maxExtract, "max-extract-size", archive.DefaultLimits.MaxTotalSize>>20, "Megabytes -extract may write in total")
+	flagSet.StringVar(&input, "input", "", "Download every UUID found in this file, one log per line or search NDJSON (- for stdin)")
+	flagSet.StringVar(&outputDir, "output-dir", ".", "Directory holding one subdirectory per UUID when downloading several logs")
+	flagSet.IntVar(&parallel, "parallel", 4, "Concurrent downloads when downloading several logs")
+	flagSet.IntVar(&retries, "retries", 2, "Extra attempts per log when downloading several logs")
+	flagSet.BoolVar(&skip, "skip-existing", false, "Skip logs already downloaded whose checksum file still matches")
+
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+	quiet := common.quiet
 
-	if len(args) < 1 {
-		fmt.Println("Usage: cliscore download [options] <uuid>")
-		flag.PrintDefaults()
-		os.Exit(1)
+	if input != "" || flagSet.NArg() > 1 {
+		if uuid != "" || outputPath != "" || list || extractDir != "" {
+			return usageError("-uuid, -output, -list and -extract download a single log and cannot be combined with -input or several UUIDs")
+		}
+		if parallel < 1 || retries < 0 {
+			return usageError("-parallel must be at least 1 and -retries at least 0")
+		}
+		uuids, err := readUUIDs(input, flagSet.Args())
+		if err != nil {
+			return err
+		}
+		if len(uuids) == 0 {
+			return usageError("no log UUIDs found in the input")
+		}
+		return downloadMany(uuids, common, bulkDownload{
+			dir:          outputDir,
+			file:         filePath,
+			parallel:     parallel,
+			retries:      retries,
+			skipExisting: skip,
+			quiet:        quiet,
+		})
 	}
 
 	// Get UUID from flag or argument
-	if uuid == "" {
-		uuid = args[0]
+	if uuid == "" && flagSet.NArg() > 0 {
+		uuid = flagSet.Arg(0)
 	}
 
 	if uuid == "" {
-		fmt.Println("UUID is required")
-		os.Exit(1)
+		fmt.Println("Usage: cliscore download [options] <uuid>")
+		fmt.Println("       cliscore download [options] -input <file> | <uuid> <uuid>...")
+		flagSet.PrintDefaults()
+		return usageError("a log UUID is required")
+	}
+
+	if filePath != "" && (list || extractDir != "") {
+		return usageError("-list and -extract work on whole archives and cannot be combined with -file")
 	}
 
-	cfg := config.Load()
-	if apiKey != "" {
-		cfg.APIKey = apiKey
+	cfg := common.loadConfig()
+
+	// -output names the downloaded file here, so listings use the global format
+	_, formatter, err := resolveOutput(globalOutput, cfg.Output)
+	if err != nil {
+		return err
 	}
 
-	apiClient := client.New(cfg)
+	apiClient := newClient(cfg)
 
-	// Build description for spinner
-	description := fmt.Sprintf("Downloading file for UUID: %s", uuid)
+	ctx, cancel := commandContext(cfg)
+	defer cancel()
+
+	description := fmt.Sprintf("Downloading %s", uuid)
 	if filePath != "" {
-		description = fmt.Sprintf("Downloading %s from UUID: %s", filePath, uuid)
+		description = fmt.Sprintf("Downloading %s from %s", filePath, uuid)
 	}
 
-	// Start spinner if enabled
-	var spin *spinner.Spinner
-	if !quiet {
-		spin = config.CreateSpinner(description)
-		if spin != nil {
-			spin.Start()
+	opts := keyscore.DownloadOptions{UUID: uuid, File: filePath, OutputPath: outputPath}
+
+	// The bar is redrawn in place, which only works on a terminal; stdout
+	// stays free for the result lines
+	var bar *spinner.ProgressBar
+	if !quiet && cfg.SpinnerStyle != "none" && isTerminal(os.Stderr) {
+		bar = spinner.NewBytesProgressBar(description, -1)
+		bar.SetOutput(os.Stderr)
+		opts.Progress = func(p keyscore.DownloadProgress) {
+			bar.Update(p.Written, p.Total)
 		}
+		bar.Start()
 	}
 
-	err := apiClient.DownloadFile(uuid, filePath, cfg.APIKey, outputPath)
-	
-	// Stop spinner
-	if spin != nil {
-		spin.Stop()
+	result, err := apiClient.Download(ctx, opts)
+
+	if bar != nil {
+		bar.Stop()
 	}
-	
+
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+
+	// Keep stdout parseable when a listing or report follows in a format
+	status := os.Stdout
+	if formatter != nil && (list || extractDir != "") {
+		status = os.Stderr
+	}
+	fmt.Fprintf(status, "File downloaded successfully: %s\n", result.Path)
+	if !quiet {
+		if result.Resumed > 0 {
+			fmt.Fprintf(status, "Resumed after %s of %s\n", spinner.FormatBytes(result.Resumed), spinner.FormatBytes(result.Size))
+		}
+		verified := "not verified: the server sent no checksum"
+		if result.Verified {
+			verified = "verified"
+		}
+		fmt.Fprintf(status, "SHA-256: %s (%s)\n", result.SHA256, verified)
+	}
+	common.saveResults(cfg, status, result, "download", []string{uuid}, nil)
+
+	if list {
+		if err := listArchive(result.Path, formatter); err != nil {
+			return err
+		}
+	}
+
+	if extractDir != "" {
+		limits := archive.DefaultLimits
+		limits.MaxTotalSize = maxExtract << 20
+		report, err := archive.Extract(result.Path, extractDir, limits)
+		if err != nil {
+			return err
+		}
+		if formatter != nil {
+			// Rows list the refused entries; the report holds the totals too
+			records := make([]map[string]interface{}, len(report.Refused))
+			for i, refusal := range report.Refused {
+				records[i] = map[string]interface{}{"name": refusal.Name, "reason": refusal.Reason}
+			}
+			return writeOutput(os.Stdout, formatter, Document{Value: report, Records: records, Columns: []string{"name", "reason"}})
+		}
+		fmt.Printf("Extracted %d files (%s) to %s\n", report.Files, spinner.FormatBytes(report.Bytes), report.Dir)
+		if len(report.Refused) > 0 {
+			fmt.Fprintf(os.Stderr, "Refused %d entries:\n", len(report.Refused))
+			for _, refusal := range report.Refused {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", refusal.Name, refusal.Reason)
+			}
+		}
 	}
 
 	return nil
+}
+
+// downloadMany runs a bulk download with the configured client and format
+func downloadMany(uuids []string, common *commonFlags, d bulkDownload) error {
+	cfg := common.loadConfig()
+
+	_, formatter, err := resolveOutput(globalOutput, cfg.Output)
+	if err != nil {
+		return err
+	}
+	d.formatter = formatter
+	// -retries replaces the client's retries, which would multiply with it
+	cfg.MaxRetries = 0
+	d.client = newClient(cfg)
+
+	ctx, cancel := commandContext(cfg)
+	defer cancel()
+
+	manifest, err := d.run(ctx, uuids)
+	if manifest != nil {
+		common.saveResults(cfg, os.Stderr, manifest, "download", nil, nil)
+	}
+	return err
+}
+
+// archiveColumns lead every row of an archive listing
+var archiveColumns = []string{"name", "size", "compressed", "modified"}
+
+// listArchive prints the entries of the archive at file, with readable sizes
+// unless a format is chosen
+func listArchive(file string, formatter Formatter) error {
+	entries, err := archive.List(file)
+	if err != nil {
+		return err
+	}
+
+	records := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		records[i] = map[string]interface{}{
+			"name":       entry.Name,
+			"size":       entry.Size,
+			"compressed": entry.CompressedSize,
+			"modified":   entry.Modified.Format(time.DateTime),
+		}
+		if formatter == nil {
+			records[i]["size"] = spinner.FormatBytes(int64(entry.Size))
+			records[i]["compressed"] = spinner.FormatBytes(int64(entry.CompressedSize))
+		}
+	}
+
+	if formatter == nil {
+		formatter = formatters["table"]
+	}
+	return writeOutput(os.Stdout, formatter, Document{Value: entries, Records: records, Columns: archiveColumns})
 }
\ No newline at end of file
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"cliscore/internal/jobs"
+	"cliscore/pkg/keyscore"
+)
+
+// Process exit codes returned by the cliscore binary
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+
+	// API failures that scripts commonly need to tell apart
+	ExitUnauthorized        = 3
+	ExitInsufficientCredits = 4
+	ExitRateLimited         = 5
+	ExitNotFound            = 6
+
+	// ExitInterrupted follows the shell convention of 128 + SIGINT
+	ExitInterrupted = 130
+)
+
+// ErrUsage marks errors caused by missing or invalid command line arguments
+var ErrUsage = errors.New("invalid usage")
+
+// usageError wraps a message so that ExitCode reports it as a usage error
+func usageError(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, a...))
+}
+
+// parseFlags parses args into flagSet, reporting bad flags as usage errors
+func parseFlags(flagSet *flag.FlagSet, args []string) error {
+	if helpOutput != nil {
+		flagSet.SetOutput(helpOutput)
+		flagSet.Usage = func() { printOptions(helpOutput, flagSet) }
+	}
+	err := flagSet.Parse(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrUsage, err)
+}
+
+// ExitCode maps an error returned by Command.Execute to a process exit code
+func ExitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.Is(err, ErrUsage):
+		return ExitUsage
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, keyscore.ErrUnauthorized):
+		return ExitUnauthorized
+	case errors.Is(err, keyscore.ErrInsufficientCredits):
+		return ExitInsufficientCredits
+	case errors.Is(err, keyscore.ErrRateLimited):
+		return ExitRateLimited
+	case errors.Is(err, keyscore.ErrNotFound), errors.Is(err, jobs.ErrNotFound):
+		return ExitNotFound
+	default:
+		return ExitError
+	}
+}
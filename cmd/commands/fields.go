@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"strings"
+
+	"cliscore/internal/filter"
+)
+
+// recordSelector applies -filter and -fields to result rows before they are
+// printed or saved
+type recordSelector struct {
+	filter filter.Expr
+	fields []string
+}
+
+// newRecordSelector parses the -fields and -filter flags. It returns nil
+// when neither is set, so callers can keep their unfiltered output.
+func newRecordSelector(fields, expr string) (*recordSelector, error) {
+	if fields == "" && expr == "" {
+		return nil, nil
+	}
+
+	s := &recordSelector{}
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			s.fields = append(s.fields, field)
+		}
+	}
+
+	if expr != "" {
+		parsed, err := filter.Parse(expr)
+		if err != nil {
+			return nil, usageError("%v", err)
+		}
+		s.filter = parsed
+	}
+
+	return s, nil
+}
+
+// apply returns the projected record, or false if the filter rejects it
+func (s *recordSelector) apply(record map[string]interface{}) (map[string]interface{}, bool) {
+	if s.filter != nil && !s.filter.Match(record) {
+		return nil, false
+	}
+	if len(s.fields) == 0 {
+		return record, true
+	}
+
+	projected := make(map[string]interface{}, len(s.fields))
+	for _, field := range s.fields {
+		if value, ok := filter.Lookup(record, field); ok {
+			projected[field] = value
+		}
+	}
+	return projected, true
+}
+
+// applyAll filters and projects records, keeping their order
+func (s *recordSelector) applyAll(records []map[string]interface{}) []map[string]interface{} {
+	selected := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		if projected, ok := s.apply(record); ok {
+			selected = append(selected, projected)
+		}
+	}
+	return selected
+}
+
+// columns returns the selected fields in the order given, or defaults when
+// all fields are kept
+func (s *recordSelector) columns(defaults []string) []string {
+	if len(s.fields) > 0 {
+		return s.fields
+	}
+	return defaults
+}
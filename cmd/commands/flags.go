@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"cliscore/internal/config"
+)
+
+// commonFlags are the options every command talking to the API accepts
+type commonFlags struct {
+	apiKey     string
+	quiet      bool
+	save       bool
+	noSave     bool
+	resultsDir string
+}
+
+// addCommonFlags registers -api-key, -quiet, -save, -no-save and
+// -results-dir on flagSet
+func addCommonFlags(flagSet *flag.FlagSet) *commonFlags {
+	f := &commonFlags{}
+	flagSet.StringVar(&f.apiKey, "api-key", "", "API key for authentication (overrides env var)")
+	flagSet.BoolVar(&f.quiet, "quiet", false, "Quiet mode (minimal output, no spinner)")
+	flagSet.BoolVar(&f.save, "save", false, "Save results to file")
+	flagSet.BoolVar(&f.noSave, "no-save", false, "Don't save results to file")
+	flagSet.StringVar(&f.resultsDir, "results-dir", "", "Results directory (overrides config)")
+	return f
+}
+
+// loadConfig loads the configuration with the flags that were set applied
+// on top
+func (f *commonFlags) loadConfig() *config.Config {
+	cfg := config.Load()
+	if f.apiKey != "" {
+		cfg.APIKey = f.apiKey
+	}
+	if f.save {
+		cfg.SaveResults = true
+	}
+	if f.noSave {
+		cfg.SaveResults = false
+	}
+	if f.resultsDir != "" {
+		cfg.ResultsDir = f.resultsDir
+	}
+	return cfg
+}
+
+// saveResults writes data to the results directory when saving is enabled
+// and notes the file, or the failure, on notices unless quiet
+func (f *commonFlags) saveResults(cfg *config.Config, notices io.Writer, data interface{}, command string, terms, types []string) {
+	path, err := cfg.WriteResults(data, command, terms, types)
+	if f.quiet {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(notices, "Warning: Failed to save results: %v\n", err)
+	} else if path != "" {
+		fmt.Fprintf(notices, "Results saved to: %s\n", path)
+	}
+}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestCommandsParseTheirOwnArgs(t *testing.T) {
+	// Flags registered on the global flag set would panic on the second run
+	for _, command := range []Command{&MachineInfoCommand{}, &DownloadCommand{}} {
+		for run := 0; run < 2; run++ {
+			if err := command.Execute([]string{"-bogus"}); !errors.Is(err, ErrUsage) {
+				t.Errorf("%s -bogus: error = %v, expected a usage error", command.Name(), err)
+			}
+		}
+	}
+}
+
+func TestCommonFlagsLoadConfig(t *testing.T) {
+	t.Setenv("CLISCORE_API_KEY", "env-key")
+	t.Setenv("CLISCORE_SAVE_RESULTS", "true")
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	common := addCommonFlags(flagSet)
+	if err := flagSet.Parse([]string{"-api-key", "flag-key", "-no-save", "-results-dir", "out", "-quiet"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := common.loadConfig()
+	if cfg.APIKey != "flag-key" || cfg.SaveResults || cfg.ResultsDir != "out" || !common.quiet {
+		t.Errorf("loadConfig = key %q, save %v, dir %q, quiet %v; expected the flags to override the environment", cfg.APIKey, cfg.SaveResults, cfg.ResultsDir, common.quiet)
+	}
+}
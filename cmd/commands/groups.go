@@ -0,0 +1,329 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"cliscore/internal/config"
+	"cliscore/pkg/keyscore"
+)
+
+// groupColumns lead every row of a search merged from several term groups
+var groupColumns = []string{"term", "input", "type", "page", "group"}
+
+// termFlags are the options search and count share for choosing what every
+// term is searched for and as
+type termFlags struct {
+	types       string
+	noPrompt    bool
+	cidr        string
+	countryCode string
+}
+
+// addTermFlags registers the term options on flagSet
+func addTermFlags(flagSet *flag.FlagSet) *termFlags {
+	f := &termFlags{}
+	flagSet.StringVar(&f.types, "types", "", "Comma-separated types to search (e.g. 'login,url'); skips detection")
+	flagSet.BoolVar(&f.noPrompt, "yes", false, "Use detected types without prompting")
+	flagSet.BoolVar(&f.noPrompt, "no-prompt", false, "Same as -yes")
+	flagSet.StringVar(&f.cidr, "cidr", cidrAuto, "How CIDR ranges are searched: auto, expand, wildcard or none")
+	flagSet.StringVar(&f.countryCode, "country-code", "", "Calling code for phone numbers without one (overrides config)")
+	return f
+}
+
+// readCommandTerms returns the terms of a search or count: its arguments,
+// and with -input the terms read from the file too
+func readCommandTerms(command string, flagSet *flag.FlagSet, batchOpts *batchFlags) ([]string, error) {
+	terms := flagSet.Args()
+	if batchOpts.enabled() {
+		batchTerms, err := batchOpts.readTerms(terms)
+		if err != nil {
+			return nil, err
+		}
+		terms = batchTerms
+	}
+
+	if len(terms) < 1 && batchOpts.resume == "" {
+		fmt.Printf("Usage: cliscore %s [options] <terms...>\n", command)
+		flagSet.PrintDefaults()
+		return nil, usageError("at least one search term is required")
+	}
+	return terms, nil
+}
+
+// termPlan holds the terms of a search or count once their types are known
+type termPlan struct {
+	// terms are every term searched, normalized
+	terms []string
+	// types are every type searched, sorted
+	types []string
+	// groups share their types and make up one request each
+	groups []TermGroup
+	// skipped are the terms of a batch whose type could not be detected
+	skipped []string
+	// inputs maps normalized terms to the input they were given as
+	inputs map[string]string
+	// prepared are groups with the terms actually sent to the API
+	prepared []*preparedGroup
+}
+
+// prepare resolves the types of terms, or takes the remaining terms of a
+// resumed batch, then normalizes them and expands ranges and phone numbers.
+// -country-code is applied to cfg.
+func (f *termFlags) prepare(command string, terms []string, cfg *config.Config, batchOpts *batchFlags, quiet bool) (*termPlan, error) {
+	if f.countryCode != "" {
+		cfg.CountryCode = f.countryCode
+	}
+
+	typeDetector, err := newDetector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &termPlan{}
+	if batchOpts.enabled() {
+		plan.groups, plan.skipped, plan.inputs, err = batchOpts.resolve(command, terms, f.types, typeDetector)
+	} else {
+		plan.groups, err = ResolveTermTypes(terms, f.types, f.noPrompt, cfg.CountryCode, typeDetector)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Defanged indicators and IDNs are searched in their canonical form once
+	// their types are known
+	plan.groups, plan.terms, plan.inputs = normalizeGroups(plan.groups, plan.inputs, os.Stderr, quiet)
+	plan.types = groupTypes(plan.groups)
+
+	// Expanded ranges go to the API; the original terms still name saved results
+	plan.prepared, err = prepareGroups(plan.groups, plan.inputs, f.cidr, cfg.CountryCode, quiet)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// apiSession is what a search or count sends its requests with
+type apiSession struct {
+	client *keyscore.Client
+	cache  *responseCache
+	ctx    context.Context
+	cancel context.CancelFunc
+	quiet  bool
+}
+
+// newAPISession returns the client, response cache and context of one
+// command run. close must be called once the requests are done.
+func newAPISession(cfg *config.Config, noCache, refresh, quiet bool) *apiSession {
+	ctx, cancel := commandContext(cfg)
+	return &apiSession{
+		client: newClient(cfg),
+		cache:  newResponseCache(cfg, noCache, refresh),
+		ctx:    ctx,
+		cancel: cancel,
+		quiet:  quiet,
+	}
+}
+
+// close releases the context and reports the cache hits
+func (s *apiSession) close() {
+	s.cancel()
+	s.cache.report(s.quiet)
+}
+
+// preparedGroup is a term group ready to be sent to the API
+type preparedGroup struct {
+	TermGroup
+
+	// SearchTerms are the terms sent to the API, after CIDR expansion and
+	// phone variants
+	SearchTerms []string
+	// Wildcard is set when SearchTerms are wildcard terms
+	Wildcard bool
+
+	// expanded maps each term to the search terms it became
+	expanded map[string][]string
+	// inputs maps normalized terms to the input they were given as
+	inputs map[string]string
+	// seen holds SearchTerms, so duplicates are sent once
+	seen map[string]bool
+}
+
+// prepareGroups converts the terms of every group into the terms the API
+// can search for, remembering which term each search term came from. inputs
+// (which may be nil) maps normalized terms to the input they were given as.
+// IP ranges searched as wildcards go in a group of their own, so the other
+// terms of their group are still matched exactly.
+func prepareGroups(groups []TermGroup, inputs map[string]string, cidrMode, countryCode string, quiet bool) ([]*preparedGroup, error) {
+	var prepared []*preparedGroup
+	for _, group := range groups {
+		exact := newPreparedGroup(group.Types, inputs, false)
+		wildcards := newPreparedGroup(group.Types, inputs, true)
+		for _, term := range group.Terms {
+			searchTerms := []string{term}
+			wildcard := false
+			if containsType(group.Types, "ip") {
+				var err error
+				searchTerms, wildcard, err = prepareIPTerms(searchTerms, cidrMode, quiet)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if containsType(group.Types, "phone") {
+				searchTerms = preparePhoneTerms(searchTerms, countryCode)
+			}
+			if wildcard {
+				wildcards.add(term, searchTerms)
+			} else {
+				exact.add(term, searchTerms)
+			}
+		}
+		if len(exact.Terms) > 0 || len(wildcards.Terms) == 0 {
+			prepared = append(prepared, exact)
+		}
+		if len(wildcards.Terms) > 0 {
+			prepared = append(prepared, wildcards)
+		}
+	}
+	return prepared, nil
+}
+
+func newPreparedGroup(types []string, inputs map[string]string, wildcard bool) *preparedGroup {
+	return &preparedGroup{
+		TermGroup: TermGroup{Types: types},
+		Wildcard:  wildcard,
+		expanded:  make(map[string][]string),
+		inputs:    inputs,
+		seen:      make(map[string]bool),
+	}
+}
+
+// add records term and the search terms it became
+func (p *preparedGroup) add(term string, searchTerms []string) {
+	p.Terms = append(p.Terms, term)
+	p.expanded[term] = searchTerms
+	for _, searchTerm := range searchTerms {
+		if !p.seen[searchTerm] {
+			p.seen[searchTerm] = true
+			p.SearchTerms = append(p.SearchTerms, searchTerm)
+		}
+	}
+}
+
+// tag records which term and type produced a result. With several terms in
+// the group, the term is the first one found in the result; with several
+// types, the type is the result group when it names one of them. Terms
+// changed by normalization also get the input they were given as.
+func (p *preparedGroup) tag(record map[string]interface{}) {
+	term := p.termOf(record)
+	record["term"] = term
+	if input, ok := p.inputs[term]; ok {
+		record["input"] = input
+	}
+	record["type"] = p.typeOf(record)
+}
+
+func (p *preparedGroup) termOf(record map[string]interface{}) string {
+	if len(p.Terms) == 1 {
+		return p.Terms[0]
+	}
+
+	var values []string
+	for key, value := range record {
+		if key != "group" && key != "page" {
+			values = append(values, strings.ToLower(cellString(value)))
+		}
+	}
+
+	for _, term := range p.Terms {
+		for _, searchTerm := range append([]string{term}, p.expanded[term]...) {
+			needle := strings.ToLower(strings.TrimRight(searchTerm, "*"))
+			for _, value := range values {
+				if needle != "" && strings.Contains(value, needle) {
+					return term
+				}
+			}
+		}
+	}
+	return strings.Join(p.Terms, ", ")
+}
+
+func (p *preparedGroup) typeOf(record map[string]interface{}) string {
+	if len(p.Types) == 1 {
+		return p.Types[0]
+	}
+	if group, ok := record["group"].(string); ok {
+		group = normalizeType(group)
+		if containsType(p.Types, group) {
+			return group
+		}
+	}
+	return strings.Join(p.Types, ", ")
+}
+
+// mergeGroupRecords converts the responses of every group into output rows
+// tagged with their term and type, in group order. Groups without a
+// response are skipped.
+func mergeGroupRecords(groups []*preparedGroup, responses []*keyscore.SearchResponse) []map[string]interface{} {
+	var records []map[string]interface{}
+	for i, group := range groups {
+		if responses[i] == nil {
+			continue
+		}
+		for _, record := range searchRecords(responses[i]) {
+			group.tag(record)
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// groupErrors warns about every group that failed. Results of the other
+// groups are still shown, so the first failure is returned as partialErr;
+// err is only set when no group returned anything.
+func groupErrors(groups []*preparedGroup, responses []*keyscore.SearchResponse, errs []error) (partialErr, err error) {
+	succeeded := false
+	for i, group := range groups {
+		if errs[i] == nil {
+			succeeded = true
+			continue
+		}
+		if responses[i] != nil && len(responses[i].Pages) > 0 {
+			succeeded = true
+			fmt.Fprintf(os.Stderr, "Warning: search for %s stopped after %d pages: %v\n", group.label(), len(responses[i].Pages), errs[i])
+		} else {
+			// A failed group must not contribute an empty response
+			responses[i] = nil
+			fmt.Fprintf(os.Stderr, "Warning: search for %s failed: %v\n", group.label(), errs[i])
+		}
+		if partialErr == nil {
+			partialErr = errs[i]
+		}
+	}
+	if !succeeded {
+		return nil, partialErr
+	}
+	return partialErr, nil
+}
+
+// label names the group in messages
+func (p *preparedGroup) label() string {
+	return fmt.Sprintf("%s (%s)", strings.Join(p.Terms, ", "), strings.Join(p.Types, ", "))
+}
+
+// forEachGroup calls fn for every group in parallel and waits for all of them
+func forEachGroup(groups []*preparedGroup, fn func(i int, group *preparedGroup)) {
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		wg.Add(1)
+		go func(i int, group *preparedGroup) {
+			defer wg.Done()
+			fn(i, group)
+		}(i, group)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"cliscore/internal/detector"
+)
+
+func TestGroupTerms(t *testing.T) {
+	terms := []string{"alice@corp.com", "corp.com", "10.1.2.3", "bob@corp.com", "hello"}
+	groups, undetected := groupTerms(terms, detector.New())
+
+	expected := []TermGroup{
+		{Terms: []string{"alice@corp.com", "bob@corp.com"}, Types: []string{"login"}},
+		{Terms: []string{"corp.com"}, Types: []string{"email_domain"}},
+		{Terms: []string{"10.1.2.3"}, Types: []string{"ip"}},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("groupTerms groups = %+v, expected %+v", groups, expected)
+	}
+	if !reflect.DeepEqual(undetected, []string{"hello"}) {
+		t.Errorf("groupTerms undetected = %v, expected [hello]", undetected)
+	}
+}
+
+func TestPrepareGroups(t *testing.T) {
+	groups := []TermGroup{
+		{Terms: []string{"alice@corp.com", "10.1.0.0/16", "10.2.3.4"}, Types: []string{"login", "ip"}},
+		{Terms: []string{"10.0.0.0/8"}, Types: []string{"password"}},
+	}
+	prepared, err := prepareGroups(groups, nil, cidrAuto, "1", true)
+	if err != nil {
+		t.Fatalf("prepareGroups: %v", err)
+	}
+
+	expected := []struct {
+		terms       []string
+		searchTerms []string
+		wildcard    bool
+	}{
+		{[]string{"alice@corp.com", "10.2.3.4"}, []string{"alice@corp.com", "10.2.3.4"}, false},
+		{[]string{"10.1.0.0/16"}, []string{"10.1.*"}, true},
+		{[]string{"10.0.0.0/8"}, []string{"10.0.0.0/8"}, false},
+	}
+	if len(prepared) != len(expected) {
+		t.Fatalf("prepareGroups returned %d groups, expected %d", len(prepared), len(expected))
+	}
+	for i, group := range prepared {
+		if !reflect.DeepEqual(group.Terms, expected[i].terms) || !reflect.DeepEqual(group.SearchTerms, expected[i].searchTerms) || group.Wildcard != expected[i].wildcard {
+			t.Errorf("group %d = %v searched as %v (wildcard %v), expected %v as %v (wildcard %v)",
+				i, group.Terms, group.SearchTerms, group.Wildcard, expected[i].terms, expected[i].searchTerms, expected[i].wildcard)
+		}
+	}
+}
+
+func TestPreparedGroupTag(t *testing.T) {
+	groups, err := prepareGroups([]TermGroup{{Terms: []string{"alice@corp.com", "10.1.2.3"}, Types: []string{"login", "ip"}}}, map[string]string{"alice@corp.com": "alice[@]corp[.]com"}, cidrAuto, "1", true)
+	if err != nil {
+		t.Fatalf("prepareGroups: %v", err)
+	}
+	group := groups[0]
+
+	tests := []struct {
+		record   map[string]interface{}
+		term     string
+		typeName string
+	}{
+		{map[string]interface{}{"group": "email", "login": "Alice@corp.com"}, "alice@corp.com", "login"},
+		{map[string]interface{}{"group": "ip", "ip": "10.1.2.3"}, "10.1.2.3", "ip"},
+		{map[string]interface{}{"group": "other", "value": "unrelated"}, "alice@corp.com, 10.1.2.3", "login, ip"},
+	}
+
+	for _, test := range tests {
+		group.tag(test.record)
+		if test.record["term"] != test.term || test.record["type"] != test.typeName {
+			t.Errorf("tag = %v/%v, expected %v/%v", test.record["term"], test.record["type"], test.term, test.typeName)
+		}
+		if input, ok := test.record["input"]; ok != (test.term == "alice@corp.com") || (ok && input != "alice[@]corp[.]com") {
+			t.Errorf("tag input = %v, expected the defanged input for alice@corp.com only", input)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"cliscore/internal/jobs"
+)
+
+type JobsCommand struct{}
+
+func (c *JobsCommand) Name() string {
+	return "jobs"
+}
+
+func (c *JobsCommand) Description() string {
+	return "List, inspect, resume or cancel batch jobs (list|status|resume|cancel)"
+}
+
+// jobColumns lead every row of the jobs list
+var jobColumns = []string{"id", "command", "status", "done", "total", "created", "updated", "error"}
+
+func (c *JobsCommand) Execute(args []string) error {
+	action := "list"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	flagSet := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	output := outputFlag(flagSet)
+
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+
+	store, err := jobs.DefaultStore()
+	if err != nil {
+		return err
+	}
+
+	if action == "list" {
+		return listJobs(store, *output)
+	}
+
+	if flagSet.NArg() != 1 {
+		fmt.Println("Usage: cliscore jobs [list | status <id> | resume <id> | cancel <id>] [options]")
+		flagSet.PrintDefaults()
+		return usageError("jobs %s requires exactly one job ID", action)
+	}
+	id := flagSet.Arg(0)
+
+	switch action {
+	case "status":
+		return showJob(store, id, *output)
+	case "resume":
+		return resumeJob(store, id)
+	case "cancel":
+		if _, err := store.Cancel(id); err != nil {
+			return err
+		}
+		fmt.Printf("Cancelled job %s\n", id)
+		return nil
+	}
+	return usageError("unknown jobs action %q (available: list, status, resume, cancel)", action)
+}
+
+func listJobs(store *jobs.Store, output string) error {
+	_, formatter, err := resolveOutput(output, "")
+	if err != nil {
+		return err
+	}
+
+	list, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 && formatter == nil {
+		fmt.Println("No batch jobs found")
+		return nil
+	}
+
+	records := make([]map[string]interface{}, len(list))
+	for i, job := range list {
+		records[i] = map[string]interface{}{
+			"id":      job.ID,
+			"command": job.Command,
+			"status":  job.Status,
+			"done":    len(job.Terms) - job.Remaining(),
+			"total":   len(job.Terms),
+			"created": job.Created.Format(time.DateTime),
+			"updated": job.Updated.Format(time.DateTime),
+		}
+		if job.Error != "" {
+			records[i]["error"] = job.Error
+		}
+	}
+
+	if formatter == nil {
+		formatter = formatters["table"]
+	}
+	return writeOutput(os.Stdout, formatter, Document{Value: list, Records: records, Columns: jobColumns})
+}
+
+func showJob(store *jobs.Store, id, output string) error {
+	_, formatter, err := resolveOutput(output, "")
+	if err != nil {
+		return err
+	}
+
+	job, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+
+	records := make([]map[string]interface{}, len(job.Terms))
+	for i, term := range job.Terms {
+		records[i], err = termRecord(term)
+		if err != nil {
+			return err
+		}
+	}
+
+	if formatter != nil {
+		return writeOutput(os.Stdout, formatter, Document{Value: job, Records: records, Columns: batchColumns})
+	}
+
+	counts := job.Counts()
+	fmt.Printf("Job: %s\n", job.ID)
+	fmt.Printf("Command: %s\n", job.Command)
+	fmt.Printf("Status: %s\n", job.Status)
+	fmt.Printf("Terms: %d done of %d (%d with results, %d without, %d failed, %d skipped, %d pending)\n",
+		len(job.Terms)-job.Remaining(), len(job.Terms), counts[jobs.TermFound], counts[jobs.TermNone], counts[jobs.TermFailed], counts[jobs.TermSkipped], counts[jobs.TermPending])
+	fmt.Printf("Results directory: %s\n", job.Dir)
+	if job.Error != "" {
+		fmt.Printf("Last error: %s\n", job.Error)
+	}
+	fmt.Println()
+	return writeOutput(os.Stdout, formatters["table"], Document{Records: records, Columns: batchColumns})
+}
+
+// resumeJob runs the command of a job again with its original flags,
+// limited to the terms it has not finished
+func resumeJob(store *jobs.Store, id string) error {
+	job, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	if !job.Resumable() {
+		return usageError("job %s is %s with %d terms remaining; nothing to resume", id, job.Status, job.Remaining())
+	}
+
+	var command Command
+	switch job.Command {
+	case "search":
+		command = &SearchCommand{}
+	case "count":
+		command = &CountCommand{}
+	default:
+		return fmt.Errorf("job %s has unknown command %q", id, job.Command)
+	}
+	return command.Execute(append([]string{"-resume", id}, job.Args...))
+}
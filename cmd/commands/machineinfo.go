@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 
-	"cliscore/internal/client"
 	"cliscore/internal/config"
 	"cliscore/internal/spinner"
 )
@@ -21,51 +20,51 @@ func (c *MachineInfoCommand) Description() string {
 }
 
 func (c *MachineInfoCommand) Execute(args []string) error {
-	var (
-		uuid      string
-		apiKey    string
-		quiet     bool
-	)
+	var uuid string
 
-	flag.StringVar(&uuid, "uuid", "", "UUID of the log file")
-	flag.StringVar(&apiKey, "api-key", "", "API key for authentication (overrides env var)")
-	flag.BoolVar(&quiet, "quiet", false, "Quiet mode (minimal output)")
+	flagSet := flag.NewFlagSet("machineinfo", flag.ContinueOnError)
+	flagSet.StringVar(&uuid, "uuid", "", "UUID of the log file")
+	common := addCommonFlags(flagSet)
+	output := outputFlag(flagSet)
 
-	flag.Parse()
-
-	if len(args) < 1 {
-		fmt.Println("Usage: cliscore machineinfo [options] <uuid>")
-		flag.PrintDefaults()
-		os.Exit(1)
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
 	}
+	quiet := common.quiet
 
 	// Get UUID from flag or argument
-	if uuid == "" {
-		uuid = args[0]
+	if uuid == "" && flagSet.NArg() > 0 {
+		uuid = flagSet.Arg(0)
 	}
 
 	if uuid == "" {
-		fmt.Println("UUID is required")
-		os.Exit(1)
+		fmt.Println("Usage: cliscore machineinfo [options] <uuid>")
+		flagSet.PrintDefaults()
+		return usageError("a log UUID is required")
 	}
 
-	cfg := config.Load()
-	if apiKey != "" {
-		cfg.APIKey = apiKey
+	cfg := common.loadConfig()
+
+	_, formatter, err := resolveOutput(*output, cfg.Output)
+	if err != nil {
+		return err
 	}
 
-	apiClient := client.New(cfg)
+	apiClient := newClient(cfg)
+
+	ctx, cancel := commandContext(cfg)
+	defer cancel()
 
 	// Start spinner if enabled
 	var spin *spinner.Spinner
-	if !quiet {
+	if !quiet && formatter == nil {
 		spin = config.CreateSpinner(fmt.Sprintf("Retrieving machine info for UUID: %s", uuid))
 		if spin != nil {
 			spin.Start()
 		}
 	}
 
-	response, err := apiClient.GetMachineInfo(uuid, cfg.APIKey)
+	response, err := apiClient.GetMachineInfo(ctx, uuid)
 	
 	// Stop spinner
 	if spin != nil {
@@ -73,26 +72,31 @@ func (c *MachineInfoCommand) Execute(args []string) error {
 	}
 	
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
 	if response.Error != "" {
-		fmt.Printf("Error: %s\n", response.Error)
-		os.Exit(1)
+		return fmt.Errorf("%s", response.Error)
 	}
 
-	if !quiet {
+	notices := os.Stdout
+	if formatter != nil {
+		notices = os.Stderr
+		record, err := valueRecord(response.Data)
+		if err != nil {
+			return err
+		}
+		doc := Document{Value: response.Data, Records: []map[string]interface{}{record}}
+		if err := writeOutput(os.Stdout, formatter, doc); err != nil {
+			return err
+		}
+	} else if !quiet {
 		fmt.Println("Machine Information:")
 		PrettyPrint(response.Data)
 	}
 
 	// Save results if enabled
-	if err := config.SaveResults(response.Data, "machineinfo", []string{uuid}, []string{"log"}); err != nil {
-		if !quiet {
-			fmt.Printf("Warning: Failed to save results: %v\n", err)
-		}
-	}
+	common.saveResults(cfg, notices, response.Data, "machineinfo", []string{uuid}, []string{"log"})
 
 	return nil
 }
\ No newline at end of file
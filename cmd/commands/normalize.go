@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"cliscore/internal/detector"
+)
+
+// hostTypes are the types whose terms hold a hostname. Only groups searched
+// for these types alone are normalized, so passwords, usernames, hashes and
+// other literal terms are always searched exactly as given.
+var hostTypes = []string{"url", "login", "email_domain"}
+
+// normalizeGroups refangs the terms of groups searched for hostTypes and
+// puts their hostnames in canonical form. It returns the groups with their
+// terms normalized, without duplicates, every term searched, and inputs
+// (created when nil) with the input each changed term was given as. Changes
+// and the Unicode form of IDNs are written to w unless quiet.
+func normalizeGroups(groups []TermGroup, inputs map[string]string, w io.Writer, quiet bool) ([]TermGroup, []string, map[string]string) {
+	if inputs == nil {
+		inputs = make(map[string]string)
+	}
+	var normalized []TermGroup
+	var terms []string
+	// Chunks of one group share their types, so duplicates are found across
+	// chunks too
+	seen := make(map[string]bool)
+
+	for _, group := range groups {
+		hosts := len(group.Types) > 0
+		for _, t := range group.Types {
+			hosts = hosts && containsType(hostTypes, t)
+		}
+		key := strings.Join(group.Types, ",")
+
+		var groupTerms []string
+		for _, term := range group.Terms {
+			n := detector.Normalized{Input: term, Term: term}
+			if hosts {
+				n = detector.Normalize(term)
+			}
+			if seen[key+"\x00"+n.Term] {
+				continue
+			}
+			seen[key+"\x00"+n.Term] = true
+			groupTerms = append(groupTerms, n.Term)
+
+			if n.Changed() {
+				inputs[n.Term] = n.Input
+			}
+			if quiet {
+				continue
+			}
+			if n.Changed() {
+				fmt.Fprintf(w, "Normalized %s as %s\n", n.Input, n.Term)
+			}
+			if n.Unicode != "" {
+				fmt.Fprintf(w, "IDN %s: %s\n", n.Term, n.Unicode)
+			}
+		}
+		if len(groupTerms) > 0 {
+			normalized = append(normalized, TermGroup{Terms: groupTerms, Types: group.Types})
+			terms = append(terms, groupTerms...)
+		}
+	}
+
+	return normalized, terms, inputs
+}
@@ -0,0 +1,345 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is what a command hands to a formatter. Structured formats
+// (json, yaml) render Value as a whole; row formats (ndjson, csv, tsv,
+// table) render Records, one row each.
+type Document struct {
+	Value   interface{}
+	Records []map[string]interface{}
+
+	// Columns lists the columns that lead every row, in order. Any other
+	// fields found in Records follow in alphabetical order.
+	Columns []string
+}
+
+// Formatter renders a Document in one output format
+type Formatter interface {
+	Format(w io.Writer, doc Document) error
+}
+
+// FormatterFunc adapts a function to the Formatter interface
+type FormatterFunc func(w io.Writer, doc Document) error
+
+func (f FormatterFunc) Format(w io.Writer, doc Document) error {
+	return f(w, doc)
+}
+
+// formatters is the registry of output formats selectable with --output
+var formatters = map[string]Formatter{
+	"json":   FormatterFunc(formatJSON),
+	"ndjson": FormatterFunc(formatNDJSON),
+	"csv":    FormatterFunc(func(w io.Writer, doc Document) error { return formatDelimited(w, doc, ',') }),
+	"tsv":    FormatterFunc(func(w io.Writer, doc Document) error { return formatDelimited(w, doc, '\t') }),
+	"table":  FormatterFunc(formatTable),
+	"yaml":   FormatterFunc(formatYAML),
+}
+
+// textOutput selects each command's own human-readable output
+const textOutput = "text"
+
+// globalOutput is the format chosen with the global --output flag
+var globalOutput string
+
+// RegisterFormatter adds or replaces an output format
+func RegisterFormatter(name string, formatter Formatter) {
+	formatters[name] = formatter
+}
+
+// OutputFormats returns the names of all output formats, sorted
+func OutputFormats() []string {
+	names := []string{textOutput}
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// SetOutputFormat sets the format used by commands that were not given
+// their own -output flag
+func SetOutputFormat(name string) error {
+	if _, err := lookupFormatter(name); err != nil {
+		return err
+	}
+	globalOutput = name
+	return nil
+}
+
+// outputFlag registers -output on flagSet, defaulting to the global format
+func outputFlag(flagSet *flag.FlagSet) *string {
+	usage := fmt.Sprintf("Output format (%s)", strings.Join(OutputFormats(), ", "))
+	return flagSet.String("output", globalOutput, usage)
+}
+
+// lookupFormatter returns the formatter registered under name. The text
+// format, or an empty name, returns nil: the command prints its usual output.
+func lookupFormatter(name string) (Formatter, error) {
+	if name == "" || name == textOutput {
+		return nil, nil
+	}
+	formatter, ok := formatters[name]
+	if !ok {
+		return nil, usageError("unknown output format %q (available: %s)", name, strings.Join(OutputFormats(), ", "))
+	}
+	return formatter, nil
+}
+
+// resolveOutput picks the format from the -output flag, falling back to the
+// configured default, and returns its name and formatter
+func resolveOutput(flagValue, configured string) (string, Formatter, error) {
+	name := flagValue
+	if name == "" {
+		name = configured
+	}
+	formatter, err := lookupFormatter(name)
+	return name, formatter, err
+}
+
+func formatJSON(w io.Writer, doc Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc.Value)
+}
+
+func formatNDJSON(w io.Writer, doc Document) error {
+	columns := recordColumns(doc)
+	encoder := json.NewEncoder(w)
+	for _, record := range doc.Records {
+		if err := encoder.Encode(orderedRecord{record, columns}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatDelimited(w io.Writer, doc Document, delimiter rune) error {
+	columns := recordColumns(doc)
+
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+	for _, record := range doc.Records {
+		for i, column := range columns {
+			row[i] = cellString(record[column])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// maxCellWidth is the widest a table column gets before values are truncated
+const maxCellWidth = 40
+
+func formatTable(w io.Writer, doc Document) error {
+	columns := recordColumns(doc)
+	if len(columns) == 0 {
+		return nil
+	}
+
+	rows := make([][]string, 0, len(doc.Records)+1)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	rows = append(rows, header)
+	for _, record := range doc.Records {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = truncate(cellString(record[column]), maxCellWidth)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		if _, err := fmt.Fprintln(w, line.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncate shortens s to at most width runes, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// recordColumns returns the leading columns that occur in the records,
+// followed by every other field sorted so the layout is stable between runs.
+// Without records the leading columns alone form the header.
+func recordColumns(doc Document) []string {
+	present := make(map[string]bool)
+	for _, record := range doc.Records {
+		for key := range record {
+			present[key] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	for _, column := range doc.Columns {
+		if !seen[column] && (present[column] || len(doc.Records) == 0) {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+
+	var rest []string
+	for key := range present {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(columns, rest...)
+}
+
+// cellString renders a value for a single csv, tsv or table cell
+func cellString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
+
+// orderedRecord marshals a record with its keys in column order
+type orderedRecord struct {
+	fields  map[string]interface{}
+	columns []string
+}
+
+// newOrderedRecord orders a single record: leading columns first, then its
+// other fields sorted
+func newOrderedRecord(record map[string]interface{}, leading []string) orderedRecord {
+	return orderedRecord{record, recordColumns(Document{Records: []map[string]interface{}{record}, Columns: leading})}
+}
+
+func (r orderedRecord) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	first := true
+	for _, column := range r.columns {
+		value, ok := r.fields[column]
+		if !ok {
+			continue
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(data)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+// flattenRecord turns nested objects into dotted keys so every field of a
+// record can become a column. Scalar records are stored under "value".
+func flattenRecord(into map[string]interface{}, prefix string, data interface{}) {
+	obj, ok := data.(map[string]interface{})
+	if !ok {
+		if prefix == "" {
+			prefix = "value"
+		}
+		into[prefix] = data
+		return
+	}
+	for key, value := range obj {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenRecord(into, key, nested)
+			continue
+		}
+		into[key] = value
+	}
+}
+
+// valueRecord converts a response struct into a single flattened row
+func valueRecord(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	record := make(map[string]interface{})
+	flattenRecord(record, "", decoded)
+	return record, nil
+}
+
+// writeOutput renders doc with formatter to w
+func writeOutput(w io.Writer, formatter Formatter, doc Document) error {
+	if err := formatter.Format(w, doc); err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"cliscore/pkg/keyscore"
+)
+
+func testDocument() Document {
+	return Document{
+		Value: map[string]interface{}{"email": []interface{}{"a@example.com"}, "size": 2},
+		Records: []map[string]interface{}{
+			{"group": "email", "url": "https://example.com/login", "login": "a@example.com"},
+			{"group": "email", "password": "p,w\"d", "page": 2},
+		},
+		Columns: []string{"page", "group"},
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"json", "{\n  \"email\": [\n    \"a@example.com\"\n  ],\n  \"size\": 2\n}\n"},
+		{"ndjson", `{"group":"email","login":"a@example.com","url":"https://example.com/login"}` + "\n" + `{"page":2,"group":"email","password":"p,w\"d"}` + "\n"},
+		{"csv", "page,group,login,password,url\n,email,a@example.com,,https://example.com/login\n2,email,,\"p,w\"\"d\",\n"},
+		{"tsv", "page\tgroup\tlogin\tpassword\turl\n\temail\ta@example.com\t\thttps://example.com/login\n2\temail\t\t\"p,w\"\"d\"\t\n"},
+		{"yaml", "email:\n  - a@example.com\nsize: 2\n"},
+	}
+
+	for _, test := range tests {
+		formatter, err := lookupFormatter(test.format)
+		if err != nil {
+			t.Fatalf("lookupFormatter(%q) returned error: %v", test.format, err)
+		}
+		var buf bytes.Buffer
+		if err := formatter.Format(&buf, testDocument()); err != nil {
+			t.Fatalf("%s: Format returned error: %v", test.format, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%s output:\n%s\nexpected:\n%s", test.format, buf.String(), test.expected)
+		}
+	}
+}
+
+func TestFormatTable(t *testing.T) {
+	doc := Document{
+		Records: []map[string]interface{}{
+			{"url": strings.Repeat("x", 60), "n": float64(1)},
+			{"url": "short", "n": float64(22)},
+		},
+		Columns: []string{"url"},
+	}
+
+	var buf bytes.Buffer
+	if err := formatTable(&buf, doc); err != nil {
+		t.Fatalf("formatTable returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := []string{
+		"URL" + strings.Repeat(" ", 39) + "N",
+		strings.Repeat("x", 39) + "…  1",
+		"short" + strings.Repeat(" ", 37) + "22",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("table has %d lines, expected %d:\n%s", len(lines), len(expected), buf.String())
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d = %q, expected %q", i, lines[i], expected[i])
+		}
+	}
+}
+
+func TestEncodeYAML(t *testing.T) {
+	value := map[string]interface{}{
+		"list":   []interface{}{map[string]interface{}{"a": 1, "b": "x"}, "plain"},
+		"quoted": []interface{}{"", "true", "123", "- dash", "key: value", "line\nbreak"},
+		"empty":  map[string]interface{}{},
+		"nested": map[string]interface{}{"deep": map[string]interface{}{"value": nil}},
+	}
+
+	expected := `empty: {}
+list:
+  - a: 1
+    b: x
+  - plain
+nested:
+  deep:
+    value: null
+quoted:
+  - ""
+  - "true"
+  - "123"
+  - "- dash"
+  - "key: value"
+  - "line\nbreak"
+`
+	data, err := encodeYAML(value)
+	if err != nil {
+		t.Fatalf("encodeYAML returned error: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("encodeYAML output:\n%s\nexpected:\n%s", data, expected)
+	}
+}
+
+func TestLookupFormatter_Unknown(t *testing.T) {
+	if _, err := lookupFormatter("xml"); err == nil || ExitCode(err) != ExitUsage {
+		t.Errorf("lookupFormatter(xml) = %v, expected a usage error", err)
+	}
+	if formatter, err := lookupFormatter("text"); formatter != nil || err != nil {
+		t.Errorf("lookupFormatter(text) = %v, %v, expected the command's own output", formatter, err)
+	}
+}
+
+func TestSearchRecords_FlattensNestedFields(t *testing.T) {
+	records := searchRecords(&keyscore.SearchResponse{
+		Results: map[string]interface{}{
+			"url": []interface{}{map[string]interface{}{"log": map[string]interface{}{"uuid": "abc"}}},
+		},
+	})
+	if len(records) != 1 {
+		t.Fatalf("got %d records, expected 1", len(records))
+	}
+	if records[0]["log.uuid"] != "abc" || records[0]["group"] != "url" {
+		t.Errorf("record = %v, expected log.uuid and group fields", records[0])
+	}
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"cliscore/internal/detector"
+)
+
+// preparePhoneTerms replaces phone numbers with the variants they are
+// commonly stored in, starting with E.164, so a number finds matches
+// whatever format the user typed it in
+func preparePhoneTerms(terms []string, countryCode string) []string {
+	var prepared []string
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		variants := []string{term}
+		if e164, ok := detector.NormalizePhone(term, countryCode); ok {
+			variants = detector.PhoneVariants(e164, countryCode)
+		}
+		for _, variant := range variants {
+			if !seen[variant] {
+				seen[variant] = true
+				prepared = append(prepared, variant)
+			}
+		}
+	}
+	return prepared
+}
+
+// describePhones writes the E.164 form of every phone number among terms
+func describePhones(w io.Writer, terms []string, countryCode string) {
+	for _, term := range terms {
+		if e164, ok := detector.NormalizePhone(term, countryCode); ok {
+			fmt.Fprintf(w, "Phone %s: %s\n", term, e164)
+		}
+	}
+}
+
+func containsType(types []string, name string) bool {
+	for _, t := range types {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreparePhoneTerms(t *testing.T) {
+	terms := preparePhoneTerms([]string{"(555) 123-4567", "+1 555 123 4567", "admin"}, "1")
+	expected := []string{"+15551234567", "15551234567", "5551234567", "admin"}
+	if !reflect.DeepEqual(terms, expected) {
+		t.Errorf("preparePhoneTerms = %v, expected %v", terms, expected)
+	}
+}
@@ -1,5 +1,12 @@
 package commands
 
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
 // Command interface defines the structure for all CLI commands
 type Command interface {
 	Name() string
@@ -12,6 +19,9 @@ func GetCommands() []Command {
 	return []Command{
 		&SearchCommand{},
 		&CountCommand{},
+		&DetectCommand{},
+		&JobsCommand{},
+		&CacheCommand{},
 		&SetupCommand{},
 		&ConfigCommand{},
 		&MachineInfoCommand{},
@@ -19,4 +29,33 @@ func GetCommands() []Command {
 		&CreditsCommand{},
 		&SpinnerCommand{},
 	}
-}
\ No newline at end of file
+}
+
+// helpOutput is set while Help runs, so the options of a command go there
+// instead of stderr
+var helpOutput io.Writer
+
+// Help writes the usage, description and options of cmd to w. The command
+// is run with -h, so it lists the options its FlagSet defines.
+func Help(cmd Command, w io.Writer) error {
+	fmt.Fprintf(w, "Usage: cliscore %s [options]\n\n", cmd.Name())
+	fmt.Fprintf(w, "%s\n", cmd.Description())
+
+	helpOutput = w
+	defer func() { helpOutput = nil }()
+	if err := cmd.Execute([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
+
+// printOptions lists the options of flagSet, if it has any
+func printOptions(w io.Writer, flagSet *flag.FlagSet) {
+	count := 0
+	flagSet.VisitAll(func(*flag.Flag) { count++ })
+	if count == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nOptions:")
+	flagSet.PrintDefaults()
+}
@@ -1,16 +1,15 @@
 package commands
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
 
-	"cliscore/internal/client"
 	"cliscore/internal/config"
-	"cliscore/internal/detector"
-	"cliscore/internal/models"
 	"cliscore/internal/spinner"
+	"cliscore/pkg/keyscore"
 )
 
 type SearchCommand struct{}
@@ -25,93 +24,112 @@ func (c *SearchCommand) Description() string {
 
 func (c *SearchCommand) Execute(args []string) error {
 	var (
-		terms        []string
-		types        []string
 		wildcard     bool
 		source       string
-		apiKey       string
-		saveResults  bool
-		noSave       bool
-		resultsDir   string
 		showSpinner  bool
-		quiet        bool
 		operator     string
 		page         int
 		pages        string
 		pageSize     int
+		all          bool
+		concurrency  int
+		maxResults   int64
+		maxCredits   int64
+		stream       bool
+		fields       string
+		filterExpr   string
 	)
 
-	flagSet := flag.NewFlagSet("search", flag.ExitOnError)
+	flagSet := flag.NewFlagSet("search", flag.ContinueOnError)
 	flagSet.StringVar(&source, "source", "xkeyscore", "Source to search from")
 	flagSet.BoolVar(&wildcard, "wildcard", false, "Enable wildcard search")
-	flagSet.StringVar(&apiKey, "api-key", "", "API key for authentication (overrides env var)")
-	flagSet.BoolVar(&saveResults, "save", false, "Save results to file")
-	flagSet.BoolVar(&noSave, "no-save", false, "Don't save results to file")
-	flagSet.StringVar(&resultsDir, "results-dir", "", "Results directory (overrides config)")
+	common := addCommonFlags(flagSet)
 	flagSet.BoolVar(&showSpinner, "spinner", true, "Show loading spinner")
-	flagSet.BoolVar(&quiet, "quiet", false, "Quiet mode (no spinner)")
 	flagSet.StringVar(&operator, "operator", "", "Search operator (AND, LOGS)")
+	termOpts := addTermFlags(flagSet)
 	flagSet.IntVar(&page, "page", 0, "Specific page number to retrieve (1-10)")
 	flagSet.StringVar(&pages, "pages", "", "Pages to retrieve (e.g., '1,2,3' or '1-5')")
 	flagSet.IntVar(&pageSize, "page-size", 0, "Number of results per page (max: 10000)")
+	flagSet.BoolVar(&all, "all", false, "Fetch every page until all results are retrieved")
+	flagSet.IntVar(&concurrency, "concurrency", 1, "Pages fetched in parallel with -all")
+	flagSet.Int64Var(&maxResults, "max-results", 0, "Stop -all once this many results are fetched (0 = no limit)")
+	flagSet.Int64Var(&maxCredits, "max-credits", 0, "Stop -all before spending more than this many credits (0 = no limit)")
+	flagSet.BoolVar(&stream, "stream", false, "Write each result as a JSON line as it arrives")
+	flagSet.StringVar(&fields, "fields", "", "Comma-separated result fields to keep (e.g. 'url,login,log.uuid')")
+	flagSet.StringVar(&filterExpr, "filter", "", "Only keep results matching an expression (e.g. 'url =~ \"login\" and not domain == example.org')")
+	output := outputFlag(flagSet)
+	noCache, refresh := addCacheFlags(flagSet)
+	batchOpts := addBatchFlags(flagSet, true)
 
-	if err := flagSet.Parse(args); err != nil {
+	if err := parseFlags(flagSet, args); err != nil {
 		return err
 	}
 	
-	terms = flagSet.Args()
-
-	if len(terms) < 1 {
-		fmt.Println("Usage: cliscore search [options] <terms...>")
-		flagSet.PrintDefaults()
-		os.Exit(1)
+	quiet := common.quiet
+	terms, err := readCommandTerms("search", flagSet, batchOpts)
+	if err != nil {
+		return err
 	}
 
-	if len(types) == 0 {
-		types = DetectOrPromptTypes(terms, detector.New())
+	if all && (page > 0 || pages != "") {
+		return usageError("-all cannot be combined with -page or -pages")
 	}
 
-	cfg := config.Load()
-	if apiKey != "" {
-		cfg.APIKey = apiKey
-	}
-	
-	if saveResults {
-		cfg.SaveResults = true
+	if stream && all {
+		return usageError("-stream cannot be combined with -all")
 	}
-	if noSave {
-		cfg.SaveResults = false
+
+	if stream && batchOpts.enabled() {
+		return usageError("-stream cannot be combined with -input")
 	}
-	if resultsDir != "" {
-		cfg.ResultsDir = resultsDir
+
+	selector, err := newRecordSelector(fields, filterExpr)
+	if err != nil {
+		return err
 	}
 
-	apiClient := client.New(cfg)
+	cfg := common.loadConfig()
 
-	mappedTypes := make([]string, len(types))
-	for i, t := range types {
-		if t == "login" {
-			mappedTypes[i] = "email"
-		} else {
-			mappedTypes[i] = t
-		}
+	format, formatter, err := resolveOutput(*output, cfg.Output)
+	if err != nil {
+		return err
+	}
+	if stream && formatter != nil && format != "ndjson" {
+		return usageError("-stream only supports ndjson output")
 	}
 
-	req := &models.SearchRequest{
-		Terms:    terms,
-		Types:    mappedTypes,
-		Wildcard: wildcard,
-		Source:   source,
+	plan, err := termOpts.prepare("search", terms, cfg, batchOpts, quiet)
+	if err != nil {
+		return err
 	}
+	terms, types, prepared := plan.terms, plan.types, plan.prepared
 
-	if operator != "" {
-		req.Operator = &operator
+	session := newAPISession(cfg, *noCache, *refresh, quiet)
+	defer session.close()
+	apiClient, cached, ctx := session.client, session.cache, session.ctx
+
+	// Each group of terms is searched only for its own types
+	requests := make([]*keyscore.SearchRequest, len(prepared))
+	for i, group := range prepared {
+		requests[i] = &keyscore.SearchRequest{
+			Terms:    group.SearchTerms,
+			Types:    apiTypes(group.Types),
+			Wildcard: wildcard || group.Wildcard,
+			Source:   source,
+		}
+		if operator != "" {
+			requests[i].Operator = &operator
+		}
 	}
 
+	// Rows name their term when several groups are merged or when terms were
+	// normalized, so results can be traced back to the input
+	grouped := len(prepared) > 1 || len(plan.inputs) > 0
+
 	// Parse pagination parameters
-	var pagination *models.SearchPaginationParams
+	var pagination *keyscore.SearchPaginationParams
 	if page > 0 || pages != "" || pageSize > 0 {
-		pagination = &models.SearchPaginationParams{}
+		pagination = &keyscore.SearchPaginationParams{}
 		
 		if page > 0 {
 			if page > 10 {
@@ -135,8 +153,33 @@ func (c *SearchCommand) Execute(args []string) error {
 		}
 	}
 
+	if stream {
+		// Records go straight to stdout, so there is nothing left to save
+		if cfg.SaveResults && !quiet {
+			fmt.Fprintln(os.Stderr, "Warning: results are not saved with -stream; redirect stdout to keep them")
+		}
+		var written, total int64
+		for i, group := range prepared {
+			var tag func(map[string]interface{})
+			if grouped {
+				tag = group.tag
+			}
+			n, size, err := streamSearch(ctx, apiClient, requests[i], pagination, selector, tag, os.Stdout)
+			written += n
+			total += size
+			if err != nil {
+				return err
+			}
+		}
+		if !quiet {
+			reportStream(written, total)
+		}
+		return nil
+	}
+
+	// The spinner draws on stdout, so it would corrupt formatted output
 	var spin *spinner.Spinner
-	if showSpinner && !quiet {
+	if showSpinner && !quiet && !all && formatter == nil && !batchOpts.enabled() {
 		searchMsg := fmt.Sprintf("Searching for %s in %s...", strings.Join(terms, ", "), strings.Join(types, ", "))
 		spin = config.CreateSpinner(searchMsg)
 		if spin != nil {
@@ -144,40 +187,167 @@ func (c *SearchCommand) Execute(args []string) error {
 		}
 	}
 
-	var response *models.SearchResponse
-	var err error
-	
-	if pagination != nil {
-		response, err = apiClient.SearchWithPagination(req, pagination, cfg.APIKey)
-	} else {
-		response, err = apiClient.Search(req, cfg.APIKey)
+	opts := keyscore.SearchAllOptions{
+		PageSize:    pageSize,
+		Concurrency: concurrency,
+		MaxResults:  maxResults,
+		MaxCredits:  maxCredits,
+	}
+	// Progress lines from parallel groups would overwrite each other
+	showProgress := all && !quiet && len(prepared) == 1 && !batchOpts.enabled()
+	if showProgress {
+		opts.Progress = func(p keyscore.SearchProgress) {
+			fmt.Fprintf(os.Stderr, "\rFetched page %d of %d (%s results in total)", p.PagesFetched, p.TotalPages, formatNumber(p.Total))
+		}
+	}
+
+	if batchOpts.enabled() {
+		job, err := batchOpts.start("search", args, plan.groups, plan.skipped, plan.inputs, cfg.ResultsDir)
+		if err != nil {
+			return err
+		}
+		run := &batchRun{
+			store:     batchOpts.store,
+			job:       job,
+			groups:    prepared,
+			parallel:  batchOpts.parallel,
+			formatter: formatter,
+			quiet:     quiet,
+			send: func(ctx context.Context, i int) ([]map[string]interface{}, error) {
+				response, err := cached.search(ctx, apiClient, requests[i], pagination, all, opts)
+				if response == nil {
+					return nil, err
+				}
+				records := searchRecords(response)
+				for _, record := range records {
+					prepared[i].tag(record)
+				}
+				if selector != nil {
+					records = selector.applyAll(records)
+				}
+				return records, err
+			},
+			results: func(records []map[string]interface{}) int64 {
+				return int64(len(records))
+			},
+		}
+		return run.run(ctx)
+	}
+
+	responses := make([]*keyscore.SearchResponse, len(prepared))
+	errs := make([]error, len(prepared))
+	forEachGroup(prepared, func(i int, group *preparedGroup) {
+		responses[i], errs[i] = cached.search(ctx, apiClient, requests[i], pagination, all, opts)
+	})
+	if showProgress {
+		fmt.Fprintln(os.Stderr)
 	}
 	
 	if spin != nil {
 		spin.Stop()
 	}
+
+	var response *keyscore.SearchResponse
+	// partialErr is reported after displaying the results fetched before it
+	var partialErr error
+
+	if len(prepared) == 1 {
+		response, err = responses[0], errs[0]
+		// Keep whatever pages arrived before a failure, then report the error
+		if all && err != nil && response != nil && len(response.Pages) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: stopped after %d pages: %v\n", len(response.Pages), err)
+			partialErr, err = err, nil
+		}
+	} else {
+		partialErr, err = groupErrors(prepared, responses, errs)
+	}
 	
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
 	// Handle different response formats for paginated vs regular searches
 	var resultCount int
 	var resultsToSave interface{}
 
-	if len(response.Pages) > 0 {
+	if grouped {
+		// Results are merged into rows naming the term and type behind them
+		records := mergeGroupRecords(prepared, responses)
+		unfiltered := len(records)
+		columns := groupColumns
+		if selector != nil {
+			records = selector.applyAll(records)
+			columns = selector.columns(groupColumns)
+		}
+		resultCount = len(records)
+		resultsToSave = records
+
+		if formatter != nil {
+			doc := Document{Value: records, Records: records, Columns: columns}
+			if err := writeOutput(os.Stdout, formatter, doc); err != nil {
+				return err
+			}
+		} else if !quiet {
+			if selector != nil {
+				fmt.Printf("Found %d results, %d selected\n", unfiltered, resultCount)
+			} else {
+				fmt.Printf("Found %d results\n", resultCount)
+			}
+			fmt.Printf("Search Results:\n")
+			PrettyPrint(records)
+		} else {
+			fmt.Printf("%d\n", resultCount)
+		}
+	} else if selector != nil {
+		// Filtered results are plain rows, whatever the response layout
+		unfiltered := searchRecords(response)
+		records := selector.applyAll(unfiltered)
+		resultCount = len(records)
+		resultsToSave = records
+
+		if formatter != nil {
+			doc := Document{Value: records, Records: records, Columns: selector.columns(searchColumns)}
+			if err := writeOutput(os.Stdout, formatter, doc); err != nil {
+				return err
+			}
+		} else if !quiet {
+			fmt.Printf("Found %d results, %d selected\n", len(unfiltered), resultCount)
+			fmt.Printf("Search Results:\n")
+			PrettyPrint(records)
+		} else {
+			fmt.Printf("%d\n", resultCount)
+		}
+	} else if formatter != nil {
+		resultsToSave = response.Results
+		if len(response.Pages) > 0 {
+			resultsToSave = response
+		}
+		doc := Document{Value: resultsToSave, Records: searchRecords(response), Columns: searchColumns}
+		if err := writeOutput(os.Stdout, formatter, doc); err != nil {
+			return err
+		}
+		if all && !quiet {
+			if totalPages := expectedPages(response.Size, pageSize); len(response.Pages) < totalPages {
+				fmt.Fprintf(os.Stderr, "Stopped after %d of %d pages (ceiling reached)\n", len(response.Pages), totalPages)
+			}
+		}
+	} else if len(response.Pages) > 0 {
 		// Paginated response
 		resultCount = int(response.Size)
 		
 		if !quiet {
 			fmt.Printf("🔍 Search Results (Paginated)\n")
 			fmt.Printf("%s", formatPaginationInfo(response))
+			if all {
+				if totalPages := expectedPages(response.Size, pageSize); len(response.Pages) < totalPages {
+					fmt.Printf("Stopped after %d of %d pages (ceiling reached)\n", len(response.Pages), totalPages)
+				}
+			}
 			
-			// Display each page's results
-			for pageNum, pageResults := range response.Pages {
+			// Display each page's results in page order
+			for _, pageNum := range sortedPageNumbers(response.Pages) {
 				fmt.Printf("\n=== Page %d ===\n", pageNum)
-				PrettyPrint(pageResults)
+				PrettyPrint(response.Pages[pageNum])
 			}
 		} else {
 			// Quiet mode - just show total count
@@ -201,17 +371,25 @@ func (c *SearchCommand) Execute(args []string) error {
 		}
 	}
 
-	if cfg.SaveResults {
-		if err := config.SaveResults(resultsToSave, "search", terms, types); err != nil {
-			if !quiet {
-				fmt.Printf("Warning: Failed to save results: %v\n", err)
-			}
-		} else {
-			if !quiet {
-				fmt.Printf("Full response saved to: %s\n", config.GetResultsFilePath("search", terms, types))
-			}
-		}
+	// Keep notices off stdout when it carries formatted output
+	notices := os.Stdout
+	if formatter != nil {
+		notices = os.Stderr
 	}
+	common.saveResults(cfg, notices, resultsToSave, "search", terms, types)
 
-	return nil
-}
\ No newline at end of file
+	return partialErr
+}
+
+// runSearch sends one search request: every page with all, the requested
+// pages with pagination, or a single unpaginated search otherwise
+func runSearch(ctx context.Context, client *keyscore.Client, req *keyscore.SearchRequest, pagination *keyscore.SearchPaginationParams, all bool, opts keyscore.SearchAllOptions) (*keyscore.SearchResponse, error) {
+	switch {
+	case all:
+		return client.SearchAll(ctx, req, opts)
+	case pagination != nil:
+		return client.SearchWithPagination(ctx, req, pagination)
+	default:
+		return client.Search(ctx, req)
+	}
+}
@@ -1,11 +1,10 @@
 package commands
 
 import (
+	"flag"
 	"fmt"
-	"os"
 	"strings"
 
-	"cliscore/internal/client"
 	"cliscore/internal/config"
 )
 
@@ -20,6 +19,12 @@ func (c *SetupCommand) Description() string {
 }
 
 func (c *SetupCommand) Execute(args []string) error {
+	// No options, but -h still shows help instead of running the command
+	flagSet := flag.NewFlagSet("setup", flag.ContinueOnError)
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+
 	var baseURL, apiKey, resultsDir, spinnerStyle string
 	var saveResults bool
 	
@@ -48,18 +53,19 @@ func (c *SetupCommand) Execute(args []string) error {
 	}
 
 	if apiKey == "" {
-		fmt.Println("API key is required")
-		os.Exit(1)
+		return usageError("API key is required")
 	}
 
 	// Validate API key with the backend
 	fmt.Print("Validating API key...")
-	apiClient := client.New(cfg)
-	err := apiClient.ValidateAPIKey(apiKey)
+	apiClient := newClient(cfg)
+	ctx, cancel := commandContext(cfg)
+	defer cancel()
+	err := apiClient.ValidateAPIKey(ctx, apiKey)
 	if err != nil {
 		fmt.Printf("\n❌ API key validation failed: %v\n", err)
 		fmt.Println("Please check your API key and try again.")
-		os.Exit(1)
+		return err
 	}
 	fmt.Println(" ✅ Valid")
 
@@ -136,8 +142,7 @@ func (c *SetupCommand) Execute(args []string) error {
 	}
 
 	if err := config.SaveFullWithSpinner(baseURL, apiKey, resultsDir, saveResults, spinnerStyle); err != nil {
-		fmt.Printf("Error saving config: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("error saving config: %v", err)
 	}
 
 	fmt.Println("✅ Configuration saved successfully!")
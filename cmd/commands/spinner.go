@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"flag"
 	"fmt"
 )
 
@@ -15,6 +16,12 @@ func (c *SpinnerCommand) Description() string {
 }
 
 func (c *SpinnerCommand) Execute(args []string) error {
+	// No options, but -h still shows help instead of running the command
+	flagSet := flag.NewFlagSet("spinner", flag.ContinueOnError)
+	if err := parseFlags(flagSet, args); err != nil {
+		return err
+	}
+
 	fmt.Println("Available spinner styles:")
 	fmt.Println()
 	fmt.Println("🎨 Visual Styles:")
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"cliscore/pkg/keyscore"
+)
+
+// searchColumns lead every search result row
+var searchColumns = []string{"page", "group"}
+
+// searchRecord converts one search result into an output row: its page (for
+// paginated searches), the group it was listed under and its flattened fields
+func searchRecord(page int, group string, data interface{}) map[string]interface{} {
+	record := make(map[string]interface{})
+	flattenRecord(record, "", data)
+	if page > 0 {
+		record["page"] = page
+	}
+	record["group"] = group
+	return record
+}
+
+// searchRecords converts a buffered search response into output rows,
+// following the same rules as keyscore.ResultStream: array groups yield one
+// row per element, anything else a single row
+func searchRecords(response *keyscore.SearchResponse) []map[string]interface{} {
+	var records []map[string]interface{}
+	appendGroups := func(page int, groups map[string]interface{}) {
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			switch value := groups[name].(type) {
+			case nil:
+			case []interface{}:
+				for _, item := range value {
+					records = append(records, searchRecord(page, name, item))
+				}
+			default:
+				records = append(records, searchRecord(page, name, value))
+			}
+		}
+	}
+
+	appendGroups(0, response.Results)
+	for _, page := range sortedPageNumbers(response.Pages) {
+		appendGroups(page, response.Pages[page])
+	}
+	return records
+}
+
+// streamSearch writes each search result to out as one JSON line as soon as
+// it is decoded, so large result sets never have to fit in memory. Results
+// rejected by selector (which may be nil) are skipped. When tag is not nil
+// it adds the term and type columns of grouped searches. It returns the
+// number of records written and the total reported by the server.
+func streamSearch(ctx context.Context, client *keyscore.Client, req *keyscore.SearchRequest, pagination *keyscore.SearchPaginationParams, selector *recordSelector, tag func(map[string]interface{}), out io.Writer) (int64, int64, error) {
+	stream, err := client.SearchStream(ctx, req, pagination)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer stream.Close()
+
+	columns := searchColumns
+	if tag != nil {
+		columns = groupColumns
+	}
+	if selector != nil {
+		columns = selector.columns(columns)
+	}
+
+	encoder := json.NewEncoder(out)
+	var written int64
+	for stream.Next() {
+		rec := stream.Record()
+		record := searchRecord(rec.Page, rec.Group, rec.Data)
+		if tag != nil {
+			tag(record)
+		}
+		if selector != nil {
+			var ok bool
+			if record, ok = selector.apply(record); !ok {
+				continue
+			}
+		}
+		if err := encoder.Encode(newOrderedRecord(record, columns)); err != nil {
+			return written, 0, fmt.Errorf("error writing result: %v", err)
+		}
+		written++
+	}
+	if err := stream.Err(); err != nil {
+		return written, 0, err
+	}
+
+	// Interruptions surface as a truncated body, so report them as such
+	if ctx.Err() != nil {
+		return written, 0, ctx.Err()
+	}
+
+	return written, stream.Size(), nil
+}
+
+// reportStream prints the summary of a streamed search to stderr so it never
+// mixes with the records on stdout
+func reportStream(written, total int64) {
+	fmt.Fprintf(os.Stderr, "Streamed %s records (%s results in total)\n", formatNumber(written), formatNumber(total))
+}
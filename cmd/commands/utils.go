@@ -3,17 +3,20 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
 
-	"cliscore/internal/models"
+	"cliscore/internal/detector"
+	"cliscore/pkg/keyscore"
 )
 
 // PrettyPrint prints data with special handling for machine info file trees
 func PrettyPrint(data interface{}) {
 	// Check if this is machine info with fileTree
-	if info, ok := data.(*models.NormalizedMachineInfo); ok && len(info.FileTree) > 0 {
+	if info, ok := data.(*keyscore.NormalizedMachineInfo); ok && len(info.FileTree) > 0 {
 		// Create a copy without the fileTree for JSON printing
 		infoCopy := *info
 		fileTree := infoCopy.FileTree
@@ -111,39 +114,221 @@ func formatTreeLevel(level map[string]interface{}, prefix string) string {
 	return result.String()
 }
 
-// DetectOrPromptTypes detects data types from terms or prompts user for selection
-func DetectOrPromptTypes(terms []string, detector Detector) []string {
+// KnownTypes lists the data types that can be searched, in prompt order
+var KnownTypes = []string{"login", "password", "url", "email_domain", "username", "ip", "hash", "phone", "uuid"}
+
+// typeAliases maps type names reported by the detector to the names in KnownTypes
+var typeAliases = map[string]string{
+	"email":  "login",
+	"domain": "email_domain",
+}
+
+// normalizeTypes maps detected type names to KnownTypes, dropping duplicates
+func normalizeTypes(types []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, name := range types {
+		name = normalizeType(name)
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// normalizeType maps a detector type to the search type of the same name
+func normalizeType(name string) string {
+	if alias, ok := typeAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// parseTypes splits a comma-separated -types value and checks every name
+// against KnownTypes
+func parseTypes(value string) ([]string, error) {
+	var types []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if alias, ok := typeAliases[name]; ok {
+			name = alias
+		}
+		if !isKnownType(name) {
+			return nil, usageError("unknown type %q (available: %s)", name, strings.Join(KnownTypes, ", "))
+		}
+		types = append(types, name)
+	}
+	if len(types) == 0 {
+		return nil, usageError("-types needs at least one of: %s", strings.Join(KnownTypes, ", "))
+	}
+	return types, nil
+}
+
+func isKnownType(name string) bool {
+	for _, known := range KnownTypes {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// TermGroup is a set of terms searched for the same types
+type TermGroup struct {
+	Terms []string
+	Types []string
+}
+
+// ResolveTermTypes picks the types to search each term for: the -types
+// value for every term when given, otherwise each term's detected types.
+// Terms with the same types are grouped so they can share a request. The
+// user is only prompted when prompting is allowed and stdin is a terminal;
+// without a prompt, terms whose type cannot be detected are an error rather
+// than a hang. countryCode is used to show phone numbers in E.164 form.
+func ResolveTermTypes(terms []string, typesFlag string, noPrompt bool, countryCode string, detector Detector) ([]TermGroup, error) {
+	if typesFlag != "" {
+		describeTerms(os.Stderr, terms, countryCode)
+		types, err := parseTypes(typesFlag)
+		if err != nil {
+			return nil, err
+		}
+		return []TermGroup{{Terms: terms, Types: types}}, nil
+	}
+
+	if !noPrompt && stdinIsTerminal() {
+		return DetectOrPromptTypes(terms, countryCode, detector), nil
+	}
+
+	groups, undetected := groupTerms(terms, detector)
+	if len(undetected) > 0 {
+		return nil, usageError("could not detect the type of %s; pass -types (available: %s)", strings.Join(undetected, ", "), strings.Join(KnownTypes, ", "))
+	}
+	for _, group := range groups {
+		fmt.Fprintf(os.Stderr, "Using detected types: %s for %s\n", strings.Join(group.Types, ", "), strings.Join(group.Terms, ", "))
+	}
+	describeTerms(os.Stderr, terms, countryCode)
+	return groups, nil
+}
+
+// groupTerms detects the types of each term and groups terms with the same
+// types, in the order the terms were given. Terms whose type cannot be
+// detected are returned separately.
+func groupTerms(terms []string, detector Detector) ([]TermGroup, []string) {
+	var groups []TermGroup
+	var undetected []string
+	index := make(map[string]int)
+
+	for _, term := range terms {
+		types := normalizeTypes(detector.DetectTypes([]string{term}))
+		if len(types) == 0 {
+			undetected = append(undetected, term)
+			continue
+		}
+		key := strings.Join(types, ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, TermGroup{Types: types})
+		}
+		groups[i].Terms = append(groups[i].Terms, term)
+	}
+
+	return groups, undetected
+}
+
+// groupTypes returns every type searched by groups, sorted
+func groupTypes(groups []TermGroup) []string {
+	var types []string
+	for _, group := range groups {
+		types = append(types, group.Types...)
+	}
+	return normalizeTypes(types)
+}
+
+// apiTypes maps types to the names the API expects, like the frontend does
+// (login -> email)
+func apiTypes(types []string) []string {
+	mapped := make([]string, len(types))
+	for i, t := range types {
+		if t == "login" {
+			mapped[i] = "email"
+		} else {
+			mapped[i] = t
+		}
+	}
+	return mapped
+}
+
+// describeTerms shows what the detector made of hashes and phone numbers
+func describeTerms(w io.Writer, terms []string, countryCode string) {
+	describeHashes(w, terms)
+	describePhones(w, terms, countryCode)
+}
+
+// describeHashes writes the likely algorithms of every hash among terms
+func describeHashes(w io.Writer, terms []string) {
+	for _, term := range terms {
+		if algorithms := detector.ClassifyHash(term); algorithms != nil {
+			fmt.Fprintf(w, "Hash %s: likely %s\n", term, strings.Join(algorithms, ", "))
+		}
+	}
+}
+
+// stdinIsTerminal reports whether stdin is attached to a terminal, so cron
+// jobs and pipelines never block on a prompt
+func stdinIsTerminal() bool {
+	return isTerminal(os.Stdin)
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// DetectOrPromptTypes detects the types of each term and asks the user to
+// confirm them, falling back to one interactive selection for every term
+func DetectOrPromptTypes(terms []string, countryCode string, detector Detector) []TermGroup {
 	// Try to detect types based on the input
-	detectedTypes := detector.DetectTypes(terms)
+	groups, undetected := groupTerms(terms, detector)
 
-	if len(detectedTypes) > 0 {
-		fmt.Printf("Detected types: %v\n", detectedTypes)
+	if len(undetected) == 0 {
+		fmt.Println("Detected types:")
+		for _, group := range groups {
+			fmt.Printf("  %s: %s\n", strings.Join(group.Terms, ", "), strings.Join(group.Types, ", "))
+		}
+		describeTerms(os.Stdout, terms, countryCode)
 		fmt.Print("Use detected types? (Y/n): ")
 
 		var response string
 		fmt.Scanln(&response)
 
 		if response == "" || strings.ToLower(response) == "y" || strings.ToLower(response) == "yes" {
-			return detectedTypes
+			return groups
 		}
+	} else {
+		fmt.Printf("Could not detect the type of %s\n", strings.Join(undetected, ", "))
 	}
 
 	// Interactive type selection
-	return PromptForTypes()
+	return []TermGroup{{Terms: terms, Types: PromptForTypes()}}
 }
 
 // PromptForTypes prompts user to select data types interactively
 func PromptForTypes() []string {
 	fmt.Println("\nAvailable types:")
-	fmt.Println("1. login")
-	fmt.Println("2. password")
-	fmt.Println("3. url")
-	fmt.Println("4. email_domain")
-	fmt.Println("5. username")
-	fmt.Println("6. ip")
-	fmt.Println("7. hash")
-	fmt.Println("8. phone")
-	fmt.Println("9. uuid")
+	for i, name := range KnownTypes {
+		fmt.Printf("%d. %s\n", i+1, name)
+	}
 	fmt.Println()
 	fmt.Print("Select types (comma-separated numbers, e.g., '1,2,3' or 'all'): ")
 
@@ -153,19 +338,7 @@ func PromptForTypes() []string {
 	input = strings.ToLower(strings.TrimSpace(input))
 
 	if input == "all" {
-		return []string{"login", "password", "url", "email_domain", "username", "ip", "hash", "phone", "uuid"}
-	}
-
-	typeMap := map[string]string{
-		"1": "login",
-		"2": "password",
-		"3": "url",
-		"4": "email_domain",
-		"5": "username",
-		"6": "ip",
-		"7": "hash",
-		"8": "phone",
-		"9": "uuid",
+		return append([]string(nil), KnownTypes...)
 	}
 
 	var selectedTypes []string
@@ -173,8 +346,8 @@ func PromptForTypes() []string {
 
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
-		if typeName, exists := typeMap[part]; exists {
-			selectedTypes = append(selectedTypes, typeName)
+		if n, err := strconv.Atoi(part); err == nil && n >= 1 && n <= len(KnownTypes) {
+			selectedTypes = append(selectedTypes, KnownTypes[n-1])
 		}
 	}
 
@@ -265,7 +438,7 @@ func parsePages(pagesStr string) []int {
 }
 
 // formatPaginationInfo formats pagination information for display
-func formatPaginationInfo(response *models.SearchResponse) string {
+func formatPaginationInfo(response *keyscore.SearchResponse) string {
 	var info strings.Builder
 	
 	info.WriteString(fmt.Sprintf("Total results: %s\n", formatNumber(response.Size)))
@@ -277,11 +450,7 @@ func formatPaginationInfo(response *models.SearchResponse) string {
 	
 	// Show which pages were retrieved
 	if len(response.Pages) > 0 {
-		pages := make([]int, 0, len(response.Pages))
-		for pageNum := range response.Pages {
-			pages = append(pages, pageNum)
-		}
-		sort.Ints(pages)
+		pages := sortedPageNumbers(response.Pages)
 		
 		pageStrs := make([]string, len(pages))
 		for i, page := range pages {
@@ -293,6 +462,27 @@ func formatPaginationInfo(response *models.SearchResponse) string {
 	return info.String()
 }
 
+// sortedPageNumbers returns the page numbers of a paginated response in ascending order
+func sortedPageNumbers(pages map[int]map[string]interface{}) []int {
+	numbers := make([]int, 0, len(pages))
+	for pageNum := range pages {
+		numbers = append(numbers, pageNum)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// expectedPages returns how many pages of pageSize results hold total results
+func expectedPages(total int64, pageSize int) int {
+	if pageSize <= 0 {
+		pageSize = keyscore.DefaultPageSize
+	}
+	if pageSize > keyscore.MaxPageSize {
+		pageSize = keyscore.MaxPageSize
+	}
+	return int((total + int64(pageSize) - 1) / int64(pageSize))
+}
+
 // Detector interface for type detection
 type Detector interface {
 	DetectTypes(terms []string) []string
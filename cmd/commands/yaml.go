@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func formatYAML(w io.Writer, doc Document) error {
+	data, err := encodeYAML(doc.Value)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// encodeYAML renders value as a YAML document. Values are first normalized
+// through encoding/json so struct tags are honored and numbers stay exact.
+func encodeYAML(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var normalized interface{}
+	if err := decoder.Decode(&normalized); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	if isYAMLBlock(normalized) {
+		writeYAMLBlock(&b, normalized, 0)
+	} else {
+		b.WriteString(yamlScalar(normalized))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// isYAMLBlock reports whether value is a non-empty mapping or sequence
+func isYAMLBlock(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+// writeYAMLBlock writes a non-empty mapping or sequence, one entry per line
+func writeYAMLBlock(b *strings.Builder, value interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			b.WriteString(pad)
+			b.WriteString(yamlScalar(key))
+			b.WriteByte(':')
+			writeYAMLValue(b, v[key], indent)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if !isYAMLBlock(item) {
+				b.WriteString(pad)
+				b.WriteString("- ")
+				b.WriteString(yamlScalar(item))
+				b.WriteByte('\n')
+				continue
+			}
+			// Nested blocks start on the dash line: "- key: value"
+			var nested strings.Builder
+			writeYAMLBlock(&nested, item, indent+2)
+			b.WriteString(pad)
+			b.WriteString("- ")
+			b.WriteString(nested.String()[indent+2:])
+		}
+	}
+}
+
+// writeYAMLValue writes the value of a mapping entry after its key
+func writeYAMLValue(b *strings.Builder, value interface{}, indent int) {
+	if isYAMLBlock(value) {
+		b.WriteByte('\n')
+		writeYAMLBlock(b, value, indent+2)
+		return
+	}
+	b.WriteByte(' ')
+	b.WriteString(yamlScalar(value))
+	b.WriteByte('\n')
+}
+
+// yamlScalar renders a scalar, quoting strings that YAML would otherwise
+// read as another type or as syntax
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case map[string]interface{}:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	case string:
+		if needsYAMLQuotes(v) {
+			return strconv.Quote(v)
+		}
+		return v
+	default:
+		return strconv.Quote(fmt.Sprint(v))
+	}
+}
+
+// yamlReserved holds plain words YAML parsers resolve to non-strings
+var yamlReserved = map[string]bool{
+	"": true, "~": true, "null": true, "true": true, "false": true,
+	"yes": true, "no": true, "on": true, "off": true, "y": true, "n": true,
+}
+
+func needsYAMLQuotes(s string) bool {
+	if yamlReserved[strings.ToLower(s)] {
+		return true
+	}
+	if strings.TrimSpace(s) != s {
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+// Package archive inspects and extracts downloaded log archives.
+//
+// Log archives are untrusted, so extraction never writes outside the target
+// directory, never creates links or executable files, and stops before
+// decompression bombs fill the disk. Entries it will not extract are
+// reported rather than failing the whole archive.
+package archive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Entry describes a file in an archive
+type Entry struct {
+	Name           string    `json:"name"`
+	Size           uint64    `json:"size"`
+	CompressedSize uint64    `json:"compressedSize"`
+	Modified       time.Time `json:"modified"`
+	Dir            bool      `json:"dir,omitempty"`
+}
+
+// Limits bound what Extract writes
+type Limits struct {
+	MaxTotalSize int64   // Bytes written for all entries together
+	MaxFiles     int     // Entries extracted
+	MaxRatio     float64 // Uncompressed to compressed size of one entry
+	// RatioMinSize exempts small entries from MaxRatio, since short text
+	// files legitimately compress very well
+	RatioMinSize int64
+}
+
+// DefaultLimits suit stealer log archives, which hold many small text files
+var DefaultLimits = Limits{
+	MaxTotalSize: 4 << 30,
+	MaxFiles:     100000,
+	MaxRatio:     200,
+	RatioMinSize: 1 << 20,
+}
+
+// Refusal is an entry Extract did not write, and why
+type Refusal struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Report summarizes an extraction
+type Report struct {
+	Dir     string    `json:"dir"`
+	Files   int       `json:"files"`
+	Bytes   int64     `json:"bytes"`
+	Refused []Refusal `json:"refused,omitempty"`
+}
+
+// List returns the entries of the zip archive at file without extracting it
+func List(file string) ([]Entry, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %v", err)
+	}
+	defer r.Close()
+
+	entries := make([]Entry, len(r.File))
+	for i, f := range r.File {
+		entries[i] = Entry{
+			Name:           f.Name,
+			Size:           f.UncompressedSize64,
+			CompressedSize: f.CompressedSize64,
+			Modified:       f.Modified,
+			Dir:            f.FileInfo().IsDir(),
+		}
+	}
+	return entries, nil
+}
+
+// errLimit marks refusals caused by the size limits
+var errLimit = errors.New("limit exceeded")
+
+// Extract writes the entries of the zip archive at file below dir, within
+// limits. Files are created with mode 0644 and directories with 0755.
+func Extract(file, dir string, limits Limits) (*Report, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %v", err)
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory: %v", err)
+	}
+	report := &Report{Dir: dir}
+
+	for _, f := range r.File {
+		refuse := func(format string, args ...interface{}) {
+			report.Refused = append(report.Refused, Refusal{Name: f.Name, Reason: fmt.Sprintf(format, args...)})
+		}
+
+		name, err := safeName(f.Name)
+		if err != nil {
+			refuse("%v", err)
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		mode := f.Mode()
+		switch {
+		case mode&os.ModeSymlink != 0:
+			refuse("symbolic link")
+			continue
+		case mode.IsDir():
+			if err := mkdirAll(dir, name); err != nil {
+				refuse("%v", err)
+			}
+			continue
+		case !mode.IsRegular():
+			refuse("not a regular file (%s)", mode.Type())
+			continue
+		}
+
+		if limits.MaxFiles > 0 && report.Files >= limits.MaxFiles {
+			refuse("more than %d files", limits.MaxFiles)
+			continue
+		}
+		size := int64(f.UncompressedSize64)
+		if limits.MaxRatio > 0 && size >= limits.RatioMinSize && float64(size) > limits.MaxRatio*float64(f.CompressedSize64) {
+			refuse("compression ratio above %.0f:1", limits.MaxRatio)
+			continue
+		}
+		budget := int64(-1)
+		if limits.MaxTotalSize > 0 {
+			budget = limits.MaxTotalSize - report.Bytes
+			if size > budget {
+				refuse("total size above %d bytes", limits.MaxTotalSize)
+				continue
+			}
+		}
+
+		if err := mkdirAll(dir, path.Dir(name)); err != nil {
+			refuse("%v", err)
+			continue
+		}
+		n, err := extractFile(f, target, budget)
+		if err != nil {
+			refuse("%v", err)
+			continue
+		}
+		report.Files++
+		report.Bytes += n
+	}
+	return report, nil
+}
+
+// safeName returns the cleaned slash-separated form of an entry name, or an
+// error for names that would escape the target directory
+func safeName(name string) (string, error) {
+	// Archives made on Windows may use backslashes
+	clean := strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(clean, "/") || (len(clean) >= 2 && clean[1] == ':') {
+		return "", errors.New("absolute path")
+	}
+	for _, part := range strings.Split(clean, "/") {
+		if part == ".." {
+			return "", errors.New("path escapes the target directory")
+		}
+	}
+	clean = path.Clean(clean)
+	if clean == "." || clean == "" {
+		return "", errors.New("empty name")
+	}
+	return clean, nil
+}
+
+// mkdirAll creates the directories of name below dir, refusing to follow
+// symbolic links that were already there
+func mkdirAll(dir, name string) error {
+	current := dir
+	for _, part := range strings.Split(name, "/") {
+		if part == "." || part == "" {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			if err := os.Mkdir(current, 0755); err != nil {
+				return fmt.Errorf("error creating directory: %v", err)
+			}
+		case err != nil:
+			return fmt.Errorf("error creating directory: %v", err)
+		case !info.IsDir():
+			return fmt.Errorf("%s exists and is not a directory", part)
+		}
+	}
+	return nil
+}
+
+// extractFile writes the content of f to target, failing when it holds more
+// than it declares or more than budget bytes (-1 means no budget)
+func extractFile(f *zip.File, target string, budget int64) (int64, error) {
+	if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("%s exists and is not a regular file", filepath.Base(target))
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return 0, fmt.Errorf("error reading entry: %v", err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("error creating file: %v", err)
+	}
+
+	// The declared size can lie; never read more than it promises
+	limit := int64(f.UncompressedSize64)
+	if budget >= 0 && budget < limit {
+		limit = budget
+	}
+	n, err := io.Copy(out, io.LimitReader(rc, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > limit {
+		err = fmt.Errorf("%w: entry is larger than it declares", errLimit)
+	}
+	if err != nil {
+		os.Remove(target)
+		if errors.Is(err, errLimit) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("error writing file: %v", err)
+	}
+	return n, nil
+}
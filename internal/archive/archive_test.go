@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEntry is a file written into a test archive
+type testEntry struct {
+	name    string
+	content string
+	mode    os.FileMode
+}
+
+func writeArchive(t *testing.T, entries []testEntry) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "log.zip")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		if entry.mode != 0 {
+			header.SetMode(entry.mode)
+		}
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(entry.content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return file
+}
+
+func TestList(t *testing.T) {
+	file := writeArchive(t, []testEntry{{name: "Passwords.txt", content: "secret"}, {name: "Browsers/", mode: os.ModeDir | 0755}})
+
+	entries, err := List(file)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "Passwords.txt" || entries[0].Size != 6 || entries[0].CompressedSize == 0 || !entries[1].Dir {
+		t.Errorf("List = %+v", entries)
+	}
+}
+
+func TestExtract_RefusesUnsafeEntries(t *testing.T) {
+	file := writeArchive(t, []testEntry{
+		{name: "Passwords.txt", content: "secret"},
+		{name: "Browsers/Chrome/Cookies.txt", content: "cookie"},
+		{name: "run.sh", content: "#!/bin/sh", mode: 0755},
+		{name: "../escape.txt", content: "x"},
+		{name: `Browsers\..\..\escape.txt`, content: "x"},
+		{name: "/etc/passwd", content: "x"},
+		{name: `C:\Windows\evil.dll`, content: "x"},
+		{name: "link", content: "/etc/passwd", mode: os.ModeSymlink | 0777},
+		{name: "bomb.bin", content: strings.Repeat("\x00", 4<<20)},
+	})
+	dir := filepath.Join(t.TempDir(), "out")
+
+	report, err := Extract(file, dir, DefaultLimits)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if report.Files != 3 {
+		t.Errorf("extracted %d files, expected 3", report.Files)
+	}
+
+	reasons := map[string]string{}
+	for _, refusal := range report.Refused {
+		reasons[refusal.Name] = refusal.Reason
+	}
+	expected := map[string]string{
+		"../escape.txt":             "path escapes the target directory",
+		`Browsers\..\..\escape.txt`: "path escapes the target directory",
+		"/etc/passwd":               "absolute path",
+		`C:\Windows\evil.dll`:       "absolute path",
+		"link":                      "symbolic link",
+		"bomb.bin":                  "compression ratio above 200:1",
+	}
+	for name, reason := range expected {
+		if reasons[name] != reason {
+			t.Errorf("refusal of %s = %q, expected %q", name, reasons[name], reason)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "run.sh"))
+	if err != nil || info.Mode().Perm()&0111 != 0 {
+		t.Errorf("run.sh = %v, %v, expected a file without execute permission", info, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Browsers", "Chrome", "Cookies.txt")); err != nil || string(data) != "cookie" {
+		t.Errorf("nested file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.txt")); !os.IsNotExist(err) {
+		t.Error("an entry was written outside the target directory")
+	}
+}
+
+func TestExtract_TotalSizeLimit(t *testing.T) {
+	file := writeArchive(t, []testEntry{{name: "a.txt", content: "0123456789"}, {name: "b.txt", content: "0123456789"}})
+
+	report, err := Extract(file, t.TempDir(), Limits{MaxTotalSize: 15})
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if report.Files != 1 || report.Bytes != 10 || len(report.Refused) != 1 || report.Refused[0].Name != "b.txt" {
+		t.Errorf("report = %+v, expected b.txt refused by the size limit", report)
+	}
+}
+
+func TestExtract_EntryLargerThanDeclared(t *testing.T) {
+	content := []byte(strings.Repeat("A", 100))
+	var compressed bytes.Buffer
+	fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	fw.Write(content)
+	fw.Close()
+
+	file := filepath.Join(t.TempDir(), "log.zip")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	raw, err := w.CreateRaw(&zip.FileHeader{
+		Name:               "liar.txt",
+		Method:             zip.Deflate,
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Write(compressed.Bytes())
+	w.Close()
+	f.Close()
+
+	dir := t.TempDir()
+	report, err := Extract(file, dir, DefaultLimits)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if report.Files != 0 || len(report.Refused) != 1 {
+		t.Errorf("report = %+v, expected liar.txt refused", report)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "liar.txt")); !os.IsNotExist(err) {
+		t.Error("a truncated liar.txt was left behind")
+	}
+}
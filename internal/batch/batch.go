@@ -0,0 +1,242 @@
+// Package batch reads search terms in bulk and runs work over them in
+// bounded parallel chunks.
+//
+// Terms can come from plain text (one per line), a CSV column or a JSON
+// array. Blank lines and lines starting with # are skipped.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Input formats
+const (
+	FormatAuto  = "auto"  // JSON when the input starts with [, CSV for .csv files, lines otherwise
+	FormatLines = "lines" // One term per line
+	FormatCSV   = "csv"   // One column of a CSV file
+	FormatJSON  = "json"  // An array of strings, or of objects holding the column
+)
+
+// Options controls how terms are read
+type Options struct {
+	Format string // One of the Format constants; empty means FormatAuto
+
+	// Column selects the CSV column or JSON object field holding the terms.
+	// A number is a 1-based CSV column index, with no header row; a name
+	// picks the column from the header row. CSV defaults to the first
+	// column and JSON objects to the "term" field.
+	Column string
+}
+
+// ReadFile reads terms from path, or from stdin when path is "-"
+func ReadFile(path string, opts Options) ([]string, error) {
+	if path == "-" {
+		return Read(os.Stdin, opts)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening input: %v", err)
+	}
+	defer f.Close()
+
+	if opts.Format == "" || opts.Format == FormatAuto {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			opts.Format = FormatCSV
+		case ".json":
+			opts.Format = FormatJSON
+		}
+	}
+	return Read(f, opts)
+}
+
+// Read reads terms from r
+func Read(r io.Reader, opts Options) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input: %v", err)
+	}
+
+	format := opts.Format
+	if format == "" || format == FormatAuto {
+		format = FormatLines
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			format = FormatJSON
+		}
+	}
+
+	switch format {
+	case FormatLines:
+		return readLines(data), nil
+	case FormatCSV:
+		return readCSV(data, opts.Column)
+	case FormatJSON:
+		return readJSON(data, opts.Column)
+	}
+	return nil, fmt.Errorf("unknown input format %q (available: auto, lines, csv, json)", format)
+}
+
+func readLines(data []byte) []string {
+	var terms []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if term := strings.TrimSpace(scanner.Text()); term != "" && !strings.HasPrefix(term, "#") {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func readCSV(data []byte, column string) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CSV input: %v", err)
+	}
+
+	index := 0
+	if column != "" {
+		if n, err := strconv.Atoi(column); err == nil {
+			if n < 1 {
+				return nil, fmt.Errorf("invalid CSV column %d", n)
+			}
+			index = n - 1
+		} else {
+			if len(rows) == 0 {
+				return nil, nil
+			}
+			index = -1
+			for i, name := range rows[0] {
+				if strings.EqualFold(strings.TrimSpace(name), column) {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				return nil, fmt.Errorf("CSV input has no column %q", column)
+			}
+			rows = rows[1:]
+		}
+	}
+
+	var terms []string
+	for _, row := range rows {
+		if index < len(row) {
+			if term := strings.TrimSpace(row[index]); term != "" {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms, nil
+}
+
+func readJSON(data []byte, column string) ([]string, error) {
+	var items []interface{}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("error parsing JSON input: expected an array: %v", err)
+	}
+	if column == "" {
+		column = "term"
+	}
+
+	var terms []string
+	for i, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			item = obj[column]
+		}
+		term, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("error parsing JSON input: item %d is not a string", i+1)
+		}
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms, nil
+}
+
+// Dedupe removes repeated terms, keeping the first occurrence. Terms are
+// compared exactly, since passwords and hashes are case-sensitive.
+func Dedupe(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	unique := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// Chunk splits terms into batches of at most size terms
+func Chunk(terms []string, size int) [][]string {
+	if size < 1 {
+		size = 1
+	}
+	var chunks [][]string
+	for len(terms) > size {
+		chunks = append(chunks, terms[:size:size])
+		terms = terms[size:]
+	}
+	if len(terms) > 0 {
+		chunks = append(chunks, terms)
+	}
+	return chunks
+}
+
+// Run calls fn for each of n jobs, at most parallelism at a time, and
+// returns the error of every job. Jobs not started when ctx is done fail
+// with ctx.Err().
+func Run(ctx context.Context, n, parallelism int, fn func(ctx context.Context, i int) error) []error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	errs := make([]error, n)
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		// A slot may free up in the same instant ctx is cancelled
+		if ctx.Err() != nil {
+			for j := i; j < n; j++ {
+				errs[j] = ctx.Err()
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			errs[i] = fn(ctx, i)
+		}(i)
+	}
+
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,144 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     Options
+		expected []string
+	}{
+		{"lines", "# employees\nalice@corp.com\n\n  bob@corp.com  \n#corp.com\ncorp.com\n", Options{}, []string{"alice@corp.com", "bob@corp.com", "corp.com"}},
+		{"json strings", `["alice@corp.com", " corp.com "]`, Options{}, []string{"alice@corp.com", "corp.com"}},
+		{"json objects", `[{"term": "alice@corp.com"}, {"term": "corp.com"}]`, Options{Format: FormatJSON}, []string{"alice@corp.com", "corp.com"}},
+		{"json field", `[{"email": "alice@corp.com", "name": "Alice"}]`, Options{Column: "email"}, []string{"alice@corp.com"}},
+		{"csv first column", "alice@corp.com,Alice\n# skipped\nbob@corp.com,Bob\n", Options{Format: FormatCSV}, []string{"alice@corp.com", "bob@corp.com"}},
+		{"csv column index", "Alice,alice@corp.com\nBob,bob@corp.com\n", Options{Format: FormatCSV, Column: "2"}, []string{"alice@corp.com", "bob@corp.com"}},
+		{"csv column name", "name,Email\nAlice,alice@corp.com\nBob,\nCarol,carol@corp.com\n", Options{Format: FormatCSV, Column: "email"}, []string{"alice@corp.com", "carol@corp.com"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			terms, err := Read(strings.NewReader(test.input), test.opts)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(terms, test.expected) {
+				t.Errorf("Read = %q, expected %q", terms, test.expected)
+			}
+		})
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  Options
+		want  string
+	}{
+		{`[1, 2]`, Options{}, "item 1 is not a string"},
+		{`{"terms": []}`, Options{Format: FormatJSON}, "expected an array"},
+		{"a,b\n", Options{Format: FormatCSV, Column: "email"}, `no column "email"`},
+		{"a\n", Options{Format: "xml"}, "unknown input format"},
+	}
+
+	for _, test := range tests {
+		if _, err := Read(strings.NewReader(test.input), test.opts); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Read(%q) error = %v, expected %q", test.input, err, test.want)
+		}
+	}
+}
+
+func TestReadFile_DetectsFormatFromExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terms.csv")
+	if err := os.WriteFile(path, []byte("alice@corp.com,Alice\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	terms, err := ReadFile(path, Options{})
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !reflect.DeepEqual(terms, []string{"alice@corp.com"}) {
+		t.Errorf("ReadFile = %q, expected the first CSV column", terms)
+	}
+}
+
+func TestDedupeAndChunk(t *testing.T) {
+	terms := Dedupe([]string{"a", "B", "c", "a", "B", "d", "e"})
+	if !reflect.DeepEqual(terms, []string{"a", "B", "c", "d", "e"}) {
+		t.Errorf("Dedupe = %q", terms)
+	}
+
+	chunks := Chunk(terms, 2)
+	expected := [][]string{{"a", "B"}, {"c", "d"}, {"e"}}
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("Chunk = %q, expected %q", chunks, expected)
+	}
+
+	if unique := Dedupe([]string{"Secret", "secret"}); len(unique) != 2 {
+		t.Errorf("Dedupe(Secret, secret) = %q, expected both kept", unique)
+	}
+
+	// Appending to a chunk must not overwrite the next one
+	_ = append(chunks[0], "x")
+	if chunks[1][0] != "c" {
+		t.Error("Chunk returned overlapping slices")
+	}
+}
+
+func TestRun_BoundsParallelism(t *testing.T) {
+	var running, peak int32
+	errs := Run(context.Background(), 10, 3, func(ctx context.Context, i int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		if i == 4 {
+			return errors.New("job 4 failed")
+		}
+		return nil
+	})
+
+	if peak > 3 {
+		t.Errorf("peak parallelism = %d, expected at most 3", peak)
+	}
+	for i, err := range errs {
+		if (err != nil) != (i == 4) {
+			t.Errorf("job %d error = %v", i, err)
+		}
+	}
+}
+
+func TestRun_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started int32
+	errs := Run(ctx, 5, 1, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&started, 1)
+		cancel()
+		return nil
+	})
+
+	if started != 1 {
+		t.Errorf("started %d jobs after cancelling, expected 1", started)
+	}
+	if !errors.Is(errs[4], context.Canceled) {
+		t.Errorf("unstarted job error = %v, expected context.Canceled", errs[4])
+	}
+}
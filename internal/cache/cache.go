@@ -0,0 +1,262 @@
+// Package cache keeps API responses on disk so identical requests made
+// shortly after each other do not spend credits again.
+//
+// Entries are JSON files named by the SHA-256 hash of a canonical encoding
+// of the request. They expire after a TTL, and the least recently used
+// entries are evicted once the cache grows past its size limit.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cache is a directory of cached responses
+type Cache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+}
+
+// entry is the file format of a cached response
+type entry struct {
+	Created time.Time       `json:"created"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// Stats describes the content of a cache
+type Stats struct {
+	Dir     string    `json:"dir"`
+	Entries int       `json:"entries"`
+	Expired int       `json:"expired"`
+	Size    int64     `json:"size"`
+	MaxSize int64     `json:"maxSize"`
+	TTL     string    `json:"ttl"`
+	Oldest  time.Time `json:"oldest,omitempty"`
+	Newest  time.Time `json:"newest,omitempty"`
+}
+
+// New returns a cache in dir whose entries expire after ttl and which holds
+// at most maxSize bytes (0 means no limit)
+func New(dir string, ttl time.Duration, maxSize int64) *Cache {
+	return &Cache{dir: dir, ttl: ttl, maxSize: maxSize}
+}
+
+// DefaultDir returns ~/.keyscore-cli/cache
+func DefaultDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".keyscore-cli", "cache"), nil
+}
+
+// Key returns the cache key of v: the hex SHA-256 of its JSON encoding.
+// Maps are encoded with sorted keys, so equal values share a key.
+func Key(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cache key: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Get decodes the entry for key into v and returns when it was stored.
+// Missing, expired and unreadable entries are reported as misses.
+func (c *Cache) Get(key string, v interface{}) (time.Time, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || c.expired(e.Created, time.Now()) {
+		os.Remove(path)
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		os.Remove(path)
+		return time.Time{}, false
+	}
+
+	// The modification time records the last use for eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return e.Created, true
+}
+
+// Put stores v under key, then evicts expired entries and, past the size
+// limit, the least recently used ones
+func (c *Cache) Put(key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %v", err)
+	}
+	data, err := json.Marshal(entry{Created: time.Now(), Value: value})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %v", err)
+	}
+	if c.maxSize > 0 && int64(len(data)) > c.maxSize {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	return c.evict()
+}
+
+// Stats returns the number and size of the entries in the cache
+func (c *Cache) Stats() (Stats, error) {
+	stats := Stats{Dir: c.dir, MaxSize: c.maxSize, TTL: c.ttl.String()}
+	files, err := c.files()
+	if err != nil {
+		return stats, err
+	}
+
+	now := time.Now()
+	for _, file := range files {
+		created := file.created()
+		stats.Entries++
+		stats.Size += file.size
+		if c.expired(created, now) {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || created.Before(stats.Oldest) {
+			stats.Oldest = created
+		}
+		if created.After(stats.Newest) {
+			stats.Newest = created
+		}
+	}
+	return stats, nil
+}
+
+// Clear removes every entry and returns how many were removed
+func (c *Cache) Clear() (int, error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+	for _, file := range files {
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("failed to remove cache entry: %v", err)
+		}
+	}
+	return len(files), nil
+}
+
+func (c *Cache) evict() error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var kept []cacheFile
+	var size int64
+	for _, file := range files {
+		// An entry unused for longer than the TTL has expired too
+		if c.expired(file.used, now) {
+			os.Remove(file.path)
+			continue
+		}
+		kept = append(kept, file)
+		size += file.size
+	}
+
+	if c.maxSize <= 0 || size <= c.maxSize {
+		return nil
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].used.Before(kept[j].used)
+	})
+	for _, file := range kept {
+		if size <= c.maxSize {
+			break
+		}
+		if err := os.Remove(file.path); err == nil {
+			size -= file.size
+		}
+	}
+	return nil
+}
+
+func (c *Cache) expired(created, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(created) > c.ttl
+}
+
+// cacheFile is an entry file as seen by eviction and stats
+type cacheFile struct {
+	path string
+	size int64
+	used time.Time // Last stored or read
+}
+
+func (c *Cache) files() ([]cacheFile, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache directory: %v", err)
+	}
+
+	var files []cacheFile
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{
+			path: filepath.Join(c.dir, dirEntry.Name()),
+			size: info.Size(),
+			used: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// created reads when the entry at path was stored, falling back to its
+// last use for unreadable entries
+func (file cacheFile) created() time.Time {
+	var header struct {
+		Created time.Time `json:"created"`
+	}
+	if data, err := os.ReadFile(file.path); err == nil && json.Unmarshal(data, &header) == nil && !header.Created.IsZero() {
+		return header.Created
+	}
+	return file.used
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
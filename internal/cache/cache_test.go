@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKey_Canonical(t *testing.T) {
+	a, err := Key(map[string]interface{}{"terms": []string{"a"}, "types": []string{"email"}})
+	if err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+	b, _ := Key(map[string]interface{}{"types": []string{"email"}, "terms": []string{"a"}})
+	c, _ := Key(map[string]interface{}{"terms": []string{"b"}, "types": []string{"email"}})
+	if a != b {
+		t.Error("equal maps have different keys")
+	}
+	if a == c {
+		t.Error("different maps share a key")
+	}
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c := New(t.TempDir(), time.Hour, 0)
+
+	var value map[string]int
+	if _, ok := c.Get("missing", &value); ok {
+		t.Fatal("Get hit a missing entry")
+	}
+
+	if err := c.Put("k", map[string]int{"count": 42}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	created, ok := c.Get("k", &value)
+	if !ok || value["count"] != 42 {
+		t.Fatalf("Get = %v, %v, expected the stored value", value, ok)
+	}
+	if time.Since(created) > time.Minute {
+		t.Errorf("created = %v, expected now", created)
+	}
+}
+
+func TestCache_Expires(t *testing.T) {
+	dir := t.TempDir()
+	if err := New(dir, time.Hour, 0).Put("k", 1); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	var value int
+	if _, ok := New(dir, time.Nanosecond, 0).Get("k", &value); ok {
+		t.Error("Get hit an expired entry")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "k.json")); !os.IsNotExist(err) {
+		t.Errorf("expired entry was not removed: %v", err)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, time.Hour, 0)
+	for _, key := range []string{"a", "b"} {
+		if err := c.Put(key, "0123456789"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, "a.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// b was used long ago, a just now
+	past := time.Now().Add(-time.Minute)
+	os.Chtimes(filepath.Join(dir, "a.json"), past, past)
+	os.Chtimes(filepath.Join(dir, "b.json"), past.Add(-time.Second), past.Add(-time.Second))
+	var value string
+	c.Get("a", &value)
+
+	c = New(dir, time.Hour, 2*info.Size())
+	if err := c.Put("c", "0123456789"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	for key, kept := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key, &value); ok != kept {
+			t.Errorf("entry %s kept = %v, expected %v", key, ok, kept)
+		}
+	}
+}
+
+func TestCache_StatsAndClear(t *testing.T) {
+	c := New(t.TempDir(), time.Hour, 0)
+	for _, key := range []string{"a", "b"} {
+		if err := c.Put(key, key); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Entries != 2 || stats.Size == 0 || stats.Expired != 0 {
+		t.Errorf("Stats = %+v, expected two live entries", stats)
+	}
+
+	if n, err := c.Clear(); err != nil || n != 2 {
+		t.Errorf("Clear = %d, %v, expected 2 entries removed", n, err)
+	}
+	if stats, _ := c.Stats(); stats.Entries != 0 {
+		t.Errorf("Stats after Clear = %+v", stats)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,100 @@ type Config struct {
 	ResultsDir   string `json:"resultsDir"`
 	SaveResults  bool   `json:"saveResults"`
 	SpinnerStyle string `json:"spinnerStyle"`
+
+	// RequestTimeout bounds a single HTTP request in seconds (0 disables it).
+	// Nil means unset, so an explicit 0 in the config file is kept.
+	RequestTimeout *int `json:"requestTimeout,omitempty"`
+	// Timeout bounds a whole command invocation in seconds (0 disables it)
+	Timeout int `json:"timeout,omitempty"`
+
+	// MaxRetries is the number of retries for failed requests (-1 disables retries)
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// RateLimit caps requests per second sent by the client (0 means unlimited)
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	// RateBurst is the number of requests allowed in a burst above RateLimit
+	RateBurst int `json:"rateBurst,omitempty"`
+
+	// Output is the default output format (json, ndjson, csv, tsv, table, yaml or text)
+	Output string `json:"output,omitempty"`
+
+	// CountryCode is the calling code given to phone numbers typed without one
+	CountryCode string `json:"countryCode,omitempty"`
+
+	// DetectionRules are extra regex rules for classifying search terms
+	DetectionRules []DetectionRule `json:"detectionRules,omitempty"`
+
+	// CacheTTL is how long search and count responses are reused, in seconds (-1 disables the cache)
+	CacheTTL int `json:"cacheTTL,omitempty"`
+	// CacheMaxSize caps the response cache in megabytes (-1 means no limit)
+	CacheMaxSize int `json:"cacheMaxSize,omitempty"`
+}
+
+// DetectionRule classifies terms matching Pattern as Type, for formats only
+// a team knows about, such as internal usernames
+type DetectionRule struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Pattern    string  `json:"pattern"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// DefaultRuleConfidence is the confidence of detection rules that set none
+const DefaultRuleConfidence = 0.9
+
+// DefaultCacheTTL is the response cache lifetime in seconds used when none is configured
+const DefaultCacheTTL = 900
+
+// DefaultCacheMaxSize is the response cache size limit in megabytes used when none is configured
+const DefaultCacheMaxSize = 100
+
+// DefaultRequestTimeout is the per-request timeout in seconds used when none is configured
+const DefaultRequestTimeout = 60
+
+// DefaultMaxRetries is the number of retries used when none is configured
+const DefaultMaxRetries = 3
+
+// DefaultCountryCode is the phone calling code used when none is configured
+const DefaultCountryCode = "1"
+
+// RequestTimeoutDuration returns the per-request timeout as a time.Duration,
+// DefaultRequestTimeout when none is set
+func (c *Config) RequestTimeoutDuration() time.Duration {
+	if c.RequestTimeout == nil {
+		return DefaultRequestTimeout * time.Second
+	}
+	return time.Duration(*c.RequestTimeout) * time.Second
+}
+
+// TimeoutDuration returns the global command timeout as a time.Duration
+func (c *Config) TimeoutDuration() time.Duration {
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// CacheEnabled reports whether search and count responses are cached
+func (c *Config) CacheEnabled() bool {
+	return c.CacheTTL > 0
+}
+
+// CacheTTLDuration returns the response cache lifetime as a time.Duration
+func (c *Config) CacheTTLDuration() time.Duration {
+	return time.Duration(c.CacheTTL) * time.Second
+}
+
+// CacheMaxBytes returns the response cache size limit in bytes, or 0 for no limit
+func (c *Config) CacheMaxBytes() int64 {
+	if c.CacheMaxSize < 0 {
+		return 0
+	}
+	return int64(c.CacheMaxSize) << 20
+}
+
+// Retries returns the number of retries to attempt, treating negative values as disabled
+func (c *Config) Retries() int {
+	if c.MaxRetries < 0 {
+		return 0
+	}
+	return c.MaxRetries
 }
 
 func Load() *Config {
@@ -25,6 +120,10 @@ func Load() *Config {
 		ResultsDir:   getDefaultResultsDir(),
 		SaveResults:  false,
 		SpinnerStyle: "default",
+		MaxRetries:   DefaultMaxRetries,
+		CountryCode:  DefaultCountryCode,
+		CacheTTL:     DefaultCacheTTL,
+		CacheMaxSize: DefaultCacheMaxSize,
 	}
 
 	if config := loadFromFile(); config != nil {
@@ -33,6 +132,26 @@ func Load() *Config {
 		cfg.ResultsDir = config.ResultsDir
 		cfg.SaveResults = config.SaveResults
 		cfg.SpinnerStyle = config.SpinnerStyle
+		if config.RequestTimeout != nil && *config.RequestTimeout >= 0 {
+			cfg.RequestTimeout = config.RequestTimeout
+		}
+		cfg.Timeout = config.Timeout
+		if config.MaxRetries != 0 {
+			cfg.MaxRetries = config.MaxRetries
+		}
+		cfg.RateLimit = config.RateLimit
+		cfg.RateBurst = config.RateBurst
+		cfg.Output = config.Output
+		if config.CountryCode != "" {
+			cfg.CountryCode = config.CountryCode
+		}
+		cfg.DetectionRules = config.DetectionRules
+		if config.CacheTTL != 0 {
+			cfg.CacheTTL = config.CacheTTL
+		}
+		if config.CacheMaxSize != 0 {
+			cfg.CacheMaxSize = config.CacheMaxSize
+		}
 	}
 
 	if url := os.Getenv("CLISCORE_BASE_URL"); url != "" {
@@ -55,6 +174,56 @@ func Load() *Config {
 		cfg.SpinnerStyle = spinnerStyle
 	}
 
+	if requestTimeout := os.Getenv("CLISCORE_REQUEST_TIMEOUT"); requestTimeout != "" {
+		if seconds, err := strconv.Atoi(requestTimeout); err == nil && seconds >= 0 {
+			cfg.RequestTimeout = &seconds
+		}
+	}
+
+	if timeout := os.Getenv("CLISCORE_TIMEOUT"); timeout != "" {
+		if seconds, err := strconv.Atoi(timeout); err == nil && seconds >= 0 {
+			cfg.Timeout = seconds
+		}
+	}
+
+	if maxRetries := os.Getenv("CLISCORE_MAX_RETRIES"); maxRetries != "" {
+		if retries, err := strconv.Atoi(maxRetries); err == nil {
+			cfg.MaxRetries = retries
+		}
+	}
+
+	if rateLimit := os.Getenv("CLISCORE_RATE_LIMIT"); rateLimit != "" {
+		if rate, err := strconv.ParseFloat(rateLimit, 64); err == nil && rate >= 0 {
+			cfg.RateLimit = rate
+		}
+	}
+
+	if rateBurst := os.Getenv("CLISCORE_RATE_BURST"); rateBurst != "" {
+		if burst, err := strconv.Atoi(rateBurst); err == nil && burst >= 0 {
+			cfg.RateBurst = burst
+		}
+	}
+
+	if output := os.Getenv("CLISCORE_OUTPUT"); output != "" {
+		cfg.Output = output
+	}
+
+	if countryCode := os.Getenv("CLISCORE_COUNTRY_CODE"); countryCode != "" {
+		cfg.CountryCode = countryCode
+	}
+
+	if cacheTTL := os.Getenv("CLISCORE_CACHE_TTL"); cacheTTL != "" {
+		if seconds, err := strconv.Atoi(cacheTTL); err == nil {
+			cfg.CacheTTL = seconds
+		}
+	}
+
+	if cacheMaxSize := os.Getenv("CLISCORE_CACHE_MAX_SIZE"); cacheMaxSize != "" {
+		if size, err := strconv.Atoi(cacheMaxSize); err == nil {
+			cfg.CacheMaxSize = size
+		}
+	}
+
 	return cfg
 }
 
@@ -106,13 +275,17 @@ func SaveFullWithSpinner(baseURL, apiKey, resultsDir string, saveResults bool, s
 	}
 
 	configPath := filepath.Join(configDir, "config.json")
-	cfg := Config{
-		BaseURL:      baseURL,
-		APIKey:       apiKey,
-		ResultsDir:   resultsDir,
-		SaveResults:  saveResults,
-		SpinnerStyle: spinnerStyle,
+
+	// Keep settings that setup does not prompt for, such as timeouts
+	cfg := Config{}
+	if existing := loadFromFile(); existing != nil {
+		cfg = *existing
 	}
+	cfg.BaseURL = baseURL
+	cfg.APIKey = apiKey
+	cfg.ResultsDir = resultsDir
+	cfg.SaveResults = saveResults
+	cfg.SpinnerStyle = spinnerStyle
 
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
@@ -137,23 +310,29 @@ func ConfigFileExists() bool {
 	return err == nil
 }
 
-func SaveResults(data interface{}, command string, terms []string, types []string) error {
-	cfg := Load()
-
-	if !cfg.SaveResults {
-		return nil
+// WriteResults saves data to a timestamped file in the results directory
+// when saving is enabled and returns its path, or "" when saving is off.
+// The configuration passed in is used as is, so flag overrides apply.
+func (c *Config) WriteResults(data interface{}, command string, terms []string, types []string) (string, error) {
+	if !c.SaveResults {
+		return "", nil
 	}
 
-	if err := os.MkdirAll(cfg.ResultsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create results directory: %v", err)
+	if err := os.MkdirAll(c.ResultsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create results directory: %v", err)
 	}
 
-	timestamp := time.Now().Format("20060102-150405")
-	safeTerms := makeSafeFilename(strings.Join(terms, "_"))
-	safeTypes := makeSafeFilename(strings.Join(types, "_"))
+	// Commands without terms, such as credits, leave those parts out
+	parts := []string{command}
+	if len(terms) > 0 {
+		parts = append(parts, makeSafeFilename(strings.Join(terms, "_")))
+	}
+	if len(types) > 0 {
+		parts = append(parts, makeSafeFilename(strings.Join(types, "_")))
+	}
+	parts = append(parts, time.Now().Format("20060102-150405"))
 
-	filename := fmt.Sprintf("%s_%s_%s_%s.json", command, safeTerms, safeTypes, timestamp)
-	filePath := filepath.Join(cfg.ResultsDir, filename)
+	filePath := filepath.Join(c.ResultsDir, strings.Join(parts, "_")+".json")
 
 	resultData := map[string]interface{}{
 		"timestamp": time.Now().Format(time.RFC3339),
@@ -165,25 +344,19 @@ func SaveResults(data interface{}, command string, terms []string, types []strin
 
 	jsonData, err := json.MarshalIndent(resultData, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal results: %v", err)
+		return "", fmt.Errorf("failed to marshal results: %v", err)
 	}
 
 	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write results file: %v", err)
+		return "", fmt.Errorf("failed to write results file: %v", err)
 	}
 
-	return nil
+	return filePath, nil
 }
 
-func GetResultsFilePath(command string, terms []string, types []string) string {
-	cfg := Load()
-
-	timestamp := time.Now().Format("20060102-150405")
-	safeTerms := makeSafeFilename(strings.Join(terms, "_"))
-	safeTypes := makeSafeFilename(strings.Join(types, "_"))
-
-	filename := fmt.Sprintf("%s_%s_%s_%s.json", command, safeTerms, safeTypes, timestamp)
-	return filepath.Join(cfg.ResultsDir, filename)
+// SafeFilename replaces the characters of s that are unsafe in file names
+func SafeFilename(s string) string {
+	return makeSafeFilename(s)
 }
 
 func makeSafeFilename(s string) string {
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad_RequestTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected time.Duration
+	}{
+		{"unset", `{}`, DefaultRequestTimeout * time.Second},
+		{"configured", `{"requestTimeout": 5}`, 5 * time.Second},
+		{"disabled", `{"requestTimeout": 0}`, 0},
+		{"negative", `{"requestTimeout": -1}`, DefaultRequestTimeout * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("CLISCORE_REQUEST_TIMEOUT", "")
+			if err := os.MkdirAll(filepath.Join(home, ".keyscore-cli"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(home, ".keyscore-cli", "config.json"), []byte(test.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := Load().RequestTimeoutDuration(); got != test.expected {
+				t.Errorf("RequestTimeoutDuration = %v, expected %v", got, test.expected)
+			}
+		})
+	}
+}
@@ -1,66 +1,122 @@
 package detector
 
 import (
+	"sort"
 	"strings"
 )
 
-type Detector struct{}
+// Detector classifies search terms by running them through a set of rules
+type Detector struct {
+	rules []Rule
+}
 
+// New returns a detector with the built-in rules
 func New() *Detector {
-	return &Detector{}
+	d := &Detector{}
+	d.rules = d.defaultRules()
+	return d
 }
 
-func (d *Detector) DetectTypes(terms []string) []string {
-	types := make(map[string]bool)
+// Register adds a rule, for example a RegexRule from the config file
+func (d *Detector) Register(rule Rule) {
+	d.rules = append(d.rules, rule)
+}
 
-	for _, term := range terms {
-		// Check UUID first (highest priority)
-		if d.isUUID(term) {
-			types["uuid"] = true
-			continue // Skip other checks for UUIDs
-		}
+// Classify returns every candidate type for term, most likely first. Ties
+// are broken by type name, so the order is deterministic. The term is
+// normalized first, so defanged indicators and IDNs are recognized.
+func (d *Detector) Classify(term string) []Candidate {
+	term = Normalize(term).Term
 
-		// Check email
-		if d.isEmail(term) {
-			types["email"] = true
-		}
+	var candidates []Candidate
+	for _, rule := range d.rules {
+		candidates = append(candidates, rule.Detect(term)...)
+	}
 
-		// Check URL
-		if d.isURL(term) {
-			types["url"] = true
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
 		}
+		return candidates[i].Type < candidates[j].Type
+	})
+	return candidates
+}
 
-		// Check domain
-		if d.isDomain(term) {
-			types["email_domain"] = true
+// Best returns the most likely candidates for term: the top candidate and
+// any others with the same confidence
+func (d *Detector) Best(term string) []Candidate {
+	candidates := d.Classify(term)
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].Confidence < candidates[0].Confidence {
+			return candidates[:i]
 		}
 	}
+	return candidates
+}
+
+// DetectTypes returns the most likely type of each term, without
+// duplicates, in the order the terms were given
+func (d *Detector) DetectTypes(terms []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(terms))
 
-	// Convert map keys to slice
-	result := make([]string, 0, len(types))
-	for t := range types {
-		result = append(result, t)
+	for _, term := range terms {
+		for _, c := range d.Best(term) {
+			if !seen[c.Type] {
+				seen[c.Type] = true
+				result = append(result, c.Type)
+			}
+		}
 	}
 
 	return result
 }
 
 func (d *Detector) isEmail(term string) bool {
-	return strings.Contains(term, "@") && strings.Contains(term, ".") &&
-		!strings.HasPrefix(term, "@") && !strings.HasSuffix(term, "@") &&
-		!strings.HasPrefix(term, ".") && !strings.HasSuffix(term, ".")
+	at := strings.LastIndex(term, "@")
+	if at <= 0 || strings.HasPrefix(term, ".") {
+		return false
+	}
+
+	// The domain part needs a dot that neither starts nor ends it
+	domain := term[at+1:]
+	return strings.Contains(domain, ".") &&
+		!strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
 }
 
 func (d *Detector) isURL(term string) bool {
 	// Check for protocol pattern: (string)://(string)
-	return strings.Contains(term, "://") && len(strings.Split(term, "://")) == 2
+	parts := strings.Split(term, "://")
+	return len(parts) == 2 && parts[0] != "" && parts[1] != ""
 }
 
 func (d *Detector) isDomain(term string) bool {
-	// Must contain a dot but not be an email or URL
+	// Must contain a dot but not be an email, URL or IP address
 	return strings.Contains(term, ".") && !d.isEmail(term) && !d.isURL(term) &&
 		!strings.HasPrefix(term, ".") && !strings.HasSuffix(term, ".") &&
-		!strings.Contains(term, "://")
+		!strings.Contains(term, "://") && !d.isIP(term) && !d.isCIDR(term) &&
+		isHostname(term)
+}
+
+// isHostname checks that term is made of hostname labels and ends in a label
+// with a letter, so numbers like 555.123.4567 are not taken for domains.
+// Wildcards and underscores are allowed as they appear in stored records.
+func isHostname(term string) bool {
+	labels := strings.Split(term, ".")
+	for _, label := range labels {
+		if label == "" {
+			return false
+		}
+		for _, c := range label {
+			if !(c == '-' || c == '_' || c == '*' || c >= '0' && c <= '9' ||
+				c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c > 0x7f) {
+				return false
+			}
+		}
+	}
+	return strings.IndexFunc(labels[len(labels)-1], func(c rune) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c > 0x7f
+	}) >= 0
 }
 
 func (d *Detector) isUUID(term string) bool {
@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -175,4 +176,411 @@ func stringSlicesEqual(a, b []string) bool {
 	}
 	
 	return true
-}
\ No newline at end of file
+}
+
+func TestDetector_IP(t *testing.T) {
+	d := New()
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"10.0.0.1", true},
+		{"192.168.1.254", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"2001:db8::8a2e:370:7334", true},
+		{"fe80::1%eth0", true},
+		{"[2001:db8::1]", true},
+		{"[2001:db8::1]:443", true},
+		{"::ffff:10.0.0.1", true},
+		{"256.0.0.1", false},
+		{"10.0.0", false},
+		{"10.0.0.1.5", false},
+		{"[10.0.0.1]", false},
+		{"[2001:db8::1", false},
+		{"example.com", false},
+		{"10.0.0.0/8", false},
+	}
+
+	for _, test := range tests {
+		result := d.isIP(test.input)
+		if result != test.expected {
+			t.Errorf("isIP(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestDetector_IPsAreNotDomains(t *testing.T) {
+	d := New()
+
+	for _, input := range []string{"10.0.0.1", "192.168.0.0/16", "2001:db8::/32", "[::1]"} {
+		if d.isDomain(input) {
+			t.Errorf("isDomain(%q) = true, expected IPs not to be domains", input)
+		}
+		if result := d.DetectTypes([]string{input}); !stringSlicesEqual(result, []string{"ip"}) {
+			t.Errorf("DetectTypes([%s]) = %v, expected [ip]", input, result)
+		}
+	}
+
+	result := d.DetectTypes([]string{"10.0.0.1", "example.com"})
+	if !stringSlicesEqual(result, []string{"ip", "domain"}) {
+		t.Errorf("DetectTypes([10.0.0.1 example.com]) = %v, expected [ip domain]", result)
+	}
+}
+
+func TestParseIP_Normalizes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10.0.0.1", "10.0.0.1"},
+		{"fe80::1%eth0", "fe80::1"},
+		{"[2001:DB8:0:0::1]:443", "2001:db8::1"},
+	}
+
+	for _, test := range tests {
+		addr, ok := ParseIP(test.input)
+		if !ok || addr.String() != test.expected {
+			t.Errorf("ParseIP(%q) = %v, %v, expected %s", test.input, addr, ok, test.expected)
+		}
+	}
+}
+
+func TestExpandCIDR(t *testing.T) {
+	addrs, err := ExpandCIDR("10.0.0.5/30", 256)
+	if err != nil {
+		t.Fatalf("ExpandCIDR returned error: %v", err)
+	}
+	if !reflect.DeepEqual(addrs, []string{"10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"}) {
+		t.Errorf("ExpandCIDR(10.0.0.5/30) = %v", addrs)
+	}
+
+	addrs, err = ExpandCIDR("2001:db8::/126", 256)
+	if err != nil || len(addrs) != 4 || addrs[3] != "2001:db8::3" {
+		t.Errorf("ExpandCIDR(2001:db8::/126) = %v, %v", addrs, err)
+	}
+
+	if _, err := ExpandCIDR("10.0.0.0/16", 256); err == nil {
+		t.Error("ExpandCIDR(10.0.0.0/16) succeeded, expected the limit to be enforced")
+	}
+}
+
+func TestCIDRWildcards(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"10.0.0.0/8", []string{"10.*"}},
+		{"10.1.2.3/16", []string{"10.1.*"}},
+		{"192.168.7.0/24", []string{"192.168.7.*"}},
+		{"10.0.0.0/22", []string{"10.0.0.*", "10.0.1.*", "10.0.2.*", "10.0.3.*"}},
+		{"172.16.0.0/15", []string{"172.16.*", "172.17.*"}},
+		{"10.0.0.9/32", []string{"10.0.0.9"}},
+	}
+
+	for _, test := range tests {
+		result, err := CIDRWildcards(test.input, 256)
+		if err != nil || !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("CIDRWildcards(%q) = %v, %v, expected %v", test.input, result, err, test.expected)
+		}
+	}
+
+	if _, err := CIDRWildcards("10.0.0.0/9", 64); err == nil {
+		t.Error("CIDRWildcards(10.0.0.0/9) succeeded, expected the term limit to be enforced")
+	}
+	if _, err := CIDRWildcards("2001:db8::/32", 256); err == nil {
+		t.Error("CIDRWildcards accepted an IPv6 range")
+	}
+}
+
+func TestClassifyHash(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // most likely algorithm, empty if not a hash
+	}{
+		{"5f4dcc3b5aa765d61d8327deb882cf99", "MD5"},
+		{"8846F7EAEE8FB117AD06BDD830B7586C", "MD5"},
+		{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "SHA-1"},
+		{"5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8", "SHA-256"},
+		{"b109f3bbbc244eb82441917ed06d618b9008dd09b3befd1b5e07394c706a8bb980b1d7785e5976ec049b46df5f1326af5a2ea6d103fd07c95385ffab0cacbc86", "SHA-512"},
+		{"d14a028c2a3a2bc9476102bb288234c415a2b01f828ea62ac5b3e42f", "SHA-224"},
+		{"$2y$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", "bcrypt"},
+		{"$2b$12$KIXQJQ1YsyIR1a1Ue2kXx.1zC1ANHGbgAU9sIGhR3ut0yV1b3z5g6", "bcrypt"},
+		{"$1$saltsalt$qjXMvbEw8oaL.CzflDugX/", "md5crypt"},
+		{"$6$rounds=5000$usesomesillystri$D4IrlXatmP7rx3P3InaxBeoomnAihCKRVQP22JZ6EY47Wc6BkroIuUUBOov1i.S5KPgErtP/EN5mcO.ChWQW21", "sha512crypt"},
+		{"$argon2id$v=19$m=65536,t=3,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG", "Argon2"},
+		{"*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19", "MySQL 4.1+"},
+		{"{SSHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", "LDAP"},
+		{"$P$984478476IagS59wHZvyQMArzfx58u.", "phpass"},
+		{"ce1869f2-b922-456b-882c-58aa4ad5f266", ""},
+		{"5f4dcc3b5aa765d61d8327deb882cf9", ""},
+		{"zz4dcc3b5aa765d61d8327deb882cf99", ""},
+		{"password", ""},
+		{"$2y$10$tooshort", ""},
+	}
+
+	for _, test := range tests {
+		result := ClassifyHash(test.input)
+		got := ""
+		if len(result) > 0 {
+			got = result[0]
+		}
+		if got != test.expected {
+			t.Errorf("ClassifyHash(%q) = %v, expected %q first", test.input, result, test.expected)
+		}
+	}
+
+	if result := ClassifyHash("8846f7eaee8fb117ad06bdd830b7586c"); !reflect.DeepEqual(result, []string{"MD5", "NTLM", "MD4"}) {
+		t.Errorf("ClassifyHash(32 hex) = %v, expected every 128-bit candidate", result)
+	}
+}
+
+func TestDetector_DetectTypes_Hashes(t *testing.T) {
+	d := New()
+
+	tests := []struct {
+		input    []string
+		expected []string
+	}{
+		{[]string{"5f4dcc3b5aa765d61d8327deb882cf99"}, []string{"hash"}},
+		{[]string{"$2y$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"}, []string{"hash"}},
+		{[]string{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "admin@example.com"}, []string{"hash", "email"}},
+	}
+
+	for _, test := range tests {
+		result := d.DetectTypes(test.input)
+		if !stringSlicesEqual(result, test.expected) {
+			t.Errorf("DetectTypes(%v) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestDetector_Phone(t *testing.T) {
+	d := New()
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"+1 (555) 123-4567", true},
+		{"(555) 123-4567", true},
+		{"555.123.4567", true},
+		{"07911 123456", true},
+		{"+44 7911 123456", true},
+		{"0049-30-1234567", true},
+		{"5551234567", true},
+		{"12345", false},
+		{"+1234567890123456", false},
+		{"2024-01-02", false},
+		{"555-CALL-NOW", false},
+	}
+
+	for _, test := range tests {
+		result := d.isPhone(test.input)
+		if result != test.expected {
+			t.Errorf("isPhone(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+	}
+
+	if result := d.DetectTypes([]string{"10.0.0.1"}); !stringSlicesEqual(result, []string{"ip"}) {
+		t.Errorf("DetectTypes([10.0.0.1]) = %v, expected [ip]", result)
+	}
+	if result := d.DetectTypes([]string{"(555) 123-4567"}); !stringSlicesEqual(result, []string{"phone"}) {
+		t.Errorf("DetectTypes([(555) 123-4567]) = %v, expected [phone]", result)
+	}
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		input       string
+		countryCode string
+		expected    string
+	}{
+		{"(555) 123-4567", "1", "+15551234567"},
+		{"1-555-123-4567", "1", "+15551234567"},
+		{"+1 555 123 4567", "44", "+15551234567"},
+		{"07911 123456", "44", "+447911123456"},
+		{"07911 123456", "+44", "+447911123456"},
+		{"0049 30 1234567", "1", "+49301234567"},
+		{"030 1234567", "49", "+49301234567"},
+		{"5551234567", "", ""},
+		{"not a phone", "1", ""},
+	}
+
+	for _, test := range tests {
+		result, _ := NormalizePhone(test.input, test.countryCode)
+		if result != test.expected {
+			t.Errorf("NormalizePhone(%q, %q) = %q, expected %q", test.input, test.countryCode, result, test.expected)
+		}
+	}
+}
+
+func TestPhoneVariants(t *testing.T) {
+	tests := []struct {
+		e164        string
+		countryCode string
+		expected    []string
+	}{
+		{"+15551234567", "1", []string{"+15551234567", "15551234567", "5551234567"}},
+		{"+447911123456", "44", []string{"+447911123456", "447911123456", "07911123456"}},
+		{"+447911123456", "1", []string{"+447911123456", "447911123456"}},
+	}
+
+	for _, test := range tests {
+		result := PhoneVariants(test.e164, test.countryCode)
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("PhoneVariants(%q, %q) = %v, expected %v", test.e164, test.countryCode, result, test.expected)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	d := New()
+
+	candidates := d.Classify("example.com")
+	if len(candidates) != 2 || candidates[0].Type != "domain" || candidates[1].Type != "url" {
+		t.Fatalf("Classify(example.com) = %+v, expected domain then url", candidates)
+	}
+	if candidates[0].Confidence <= candidates[1].Confidence {
+		t.Errorf("domain confidence %v should exceed url confidence %v", candidates[0].Confidence, candidates[1].Confidence)
+	}
+
+	if candidates := d.Classify("hello"); len(candidates) != 0 {
+		t.Errorf("Classify(hello) = %+v, expected no candidates", candidates)
+	}
+
+	hash := d.Best("5f4dcc3b5aa765d61d8327deb882cf99")
+	if len(hash) != 1 || hash[0].Type != "hash" || hash[0].Rule != "hash" || hash[0].Detail == "" {
+		t.Errorf("Best(md5) = %+v, expected a hash candidate with algorithms", hash)
+	}
+}
+
+func TestDetectTypes_Deterministic(t *testing.T) {
+	terms := []string{"example.com", "admin@example.com", "https://example.com", "10.0.0.1", "5551234567"}
+	expected := []string{"domain", "email", "url", "ip", "phone"}
+
+	for i := 0; i < 20; i++ {
+		if result := DetectTypes(terms); !reflect.DeepEqual(result, expected) {
+			t.Fatalf("DetectTypes(%v) = %v, expected %v", terms, result, expected)
+		}
+	}
+}
+
+func TestRegexRule(t *testing.T) {
+	rule, err := NewRegexRule("staff-id", "username", `^emp-[0-9]{6}$`, 0.9)
+	if err != nil {
+		t.Fatalf("NewRegexRule: %v", err)
+	}
+
+	d := New()
+	d.Register(rule)
+
+	best := d.Best("emp-123456")
+	if len(best) != 1 || best[0].Type != "username" || best[0].Rule != "staff-id" || best[0].Confidence != 0.9 {
+		t.Errorf("Best(emp-123456) = %+v, expected the staff-id rule", best)
+	}
+	if best := d.Best("emp-12"); len(best) != 0 {
+		t.Errorf("Best(emp-12) = %+v, expected no candidates", best)
+	}
+
+	// Unanchored patterns still have to match the whole term
+	unanchored, err := NewRegexRule("ticket", "username", `[A-Z]+-[0-9]+`, 0.9)
+	if err != nil {
+		t.Fatalf("NewRegexRule: %v", err)
+	}
+	for term, matches := range map[string]bool{"OPS-42": true, "see OPS-42 today": false, "OPS-42x": false} {
+		if got := len(unanchored.Detect(term)) == 1; got != matches {
+			t.Errorf("Detect(%q) matched = %v, expected %v", term, got, matches)
+		}
+	}
+
+	if _, err := NewRegexRule("bad", "username", "(", 0.9); err == nil {
+		t.Error("NewRegexRule accepted an invalid pattern")
+	}
+	if _, err := NewRegexRule("bad", "username", ".", 1.5); err == nil {
+		t.Error("NewRegexRule accepted a confidence above 1")
+	}
+}
+
+func TestPunycode(t *testing.T) {
+	tests := []struct {
+		unicode string
+		ascii   string
+	}{
+		{"bücher", "bcher-kva"},
+		{"münchen", "mnchen-3ya"},
+		{"пример", "e1afmkfd"},
+		// RFC 3492 section 7.1, samples (A) and (B)
+		{"ليهمابتكلموشعربي؟", "egbpdaj6bu4bxfgehfvwxn"},
+		{"他们为什么不说中文", "ihqwcrb4cv8a8dqg056pqjye"},
+	}
+
+	for _, test := range tests {
+		encoded, err := punyEncode(test.unicode)
+		if err != nil || encoded != test.ascii {
+			t.Errorf("punyEncode(%q) = %q, %v, expected %q", test.unicode, encoded, err, test.ascii)
+		}
+		decoded, err := punyDecode(test.ascii)
+		if err != nil || decoded != test.unicode {
+			t.Errorf("punyDecode(%q) = %q, %v, expected %q", test.ascii, decoded, err, test.unicode)
+		}
+	}
+
+	if _, err := punyDecode("99999999999"); err == nil {
+		t.Error("punyDecode accepted an overflowing input")
+	}
+	if _, err := punyDecode("abc-ü"); err == nil {
+		t.Error("punyDecode accepted a non-ASCII input")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input    string
+		term     string
+		unicode  string
+		refanged bool
+	}{
+		{"hxxps://evil[.]com/login", "https://evil.com/login", "", true},
+		{"hXXp://Evil[dot]COM:8080/x", "http://evil.com:8080/x", "", true},
+		{"user[@]corp[.]com", "user@corp.com", "", true},
+		{"fxp://files(.)example(.)org", "ftp://files.example.org", "", true},
+		{"Alice@Corp.COM", "Alice@corp.com", "", false},
+		{"Example.com.", "example.com", "", false},
+		{"münchen.de", "xn--mnchen-3ya.de", "münchen.de", false},
+		{"XN--MNCHEN-3YA.de", "xn--mnchen-3ya.de", "münchen.de", false},
+		{"https://bücher.example/путь", "https://xn--bcher-kva.example/путь", "https://bücher.example/путь", false},
+		{"info@пример.рф", "info@xn--e1afmkfd.xn--p1ai", "info@пример.рф", false},
+		{"5F4DCC3B5AA765D61D8327DEB882CF99", "5F4DCC3B5AA765D61D8327DEB882CF99", "", false},
+		{"555.123.4567", "555.123.4567", "", false},
+		{"2001:DB8::1", "2001:DB8::1", "", false},
+	}
+
+	for _, test := range tests {
+		result := Normalize(test.input)
+		if result.Term != test.term || result.Unicode != test.unicode || result.Refanged != test.refanged || result.Input != test.input {
+			t.Errorf("Normalize(%q) = %+v, expected term %q, unicode %q, refanged %v", test.input, result, test.term, test.unicode, test.refanged)
+		}
+	}
+}
+
+func TestDetectTypes_Defanged(t *testing.T) {
+	tests := []struct {
+		term     string
+		expected string
+	}{
+		{"hxxps://evil[.]com", "url"},
+		{"user[@]corp[.]com", "email"},
+		{"evil[.]com", "domain"},
+		{"münchen.de", "domain"},
+		{"xn--mnchen-3ya.de", "domain"},
+	}
+
+	for _, test := range tests {
+		result := DetectTypes([]string{test.term})
+		if !reflect.DeepEqual(result, []string{test.expected}) {
+			t.Errorf("DetectTypes(%q) = %v, expected [%s]", test.term, result, test.expected)
+		}
+	}
+}
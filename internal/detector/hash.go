@@ -0,0 +1,64 @@
+package detector
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hashFormat describes one recognizable hash encoding
+type hashFormat struct {
+	match      func(term string) bool
+	algorithms []string
+}
+
+func hexOfLength(n int) func(string) bool {
+	return func(term string) bool {
+		return len(term) == n && new(Detector).isHex(term)
+	}
+}
+
+func matchRegexp(pattern string) func(string) bool {
+	re := regexp.MustCompile(pattern)
+	return re.MatchString
+}
+
+// hashFormats is checked in order; prefixed (crypt-style) formats come first
+// because they are unambiguous, bare hex digests last. Algorithms are listed
+// from most to least likely.
+var hashFormats = []hashFormat{
+	{matchRegexp(`^\$2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}$`), []string{"bcrypt"}},
+	{matchRegexp(`^\$argon2(id|i|d)\$`), []string{"Argon2"}},
+	{matchRegexp(`^\$(scrypt|7)\$`), []string{"scrypt"}},
+	{matchRegexp(`^\$y\$`), []string{"yescrypt"}},
+	{matchRegexp(`^\$1\$[^$]{0,8}\$[./0-9A-Za-z]{22}$`), []string{"md5crypt"}},
+	{matchRegexp(`^\$apr1\$[^$]{0,8}\$[./0-9A-Za-z]{22}$`), []string{"Apache MD5 (apr1)"}},
+	{matchRegexp(`^\$5\$(rounds=\d+\$)?[^$]{0,16}\$[./0-9A-Za-z]{43}$`), []string{"sha256crypt"}},
+	{matchRegexp(`^\$6\$(rounds=\d+\$)?[^$]{0,16}\$[./0-9A-Za-z]{86}$`), []string{"sha512crypt"}},
+	{matchRegexp(`^\$[PH]\$[./0-9A-Za-z]{31}$`), []string{"phpass"}},
+	{matchRegexp(`^pbkdf2_sha(1|256)\$\d+\$`), []string{"Django PBKDF2"}},
+	{matchRegexp(`^\{(SSHA|SHA|SSHA256|SSHA512|MD5|SMD5)\}[A-Za-z0-9+/=]+$`), []string{"LDAP"}},
+	{matchRegexp(`^\*[0-9A-Fa-f]{40}$`), []string{"MySQL 4.1+"}},
+	{matchRegexp(`^[0-9A-Fa-f]{32}:[0-9A-Fa-f]{32}$`), []string{"LM:NTLM"}},
+	{hexOfLength(32), []string{"MD5", "NTLM", "MD4"}},
+	{hexOfLength(40), []string{"SHA-1", "RIPEMD-160"}},
+	{hexOfLength(56), []string{"SHA-224", "SHA3-224"}},
+	{hexOfLength(64), []string{"SHA-256", "SHA3-256", "BLAKE2s"}},
+	{hexOfLength(96), []string{"SHA-384", "SHA3-384"}},
+	{hexOfLength(128), []string{"SHA-512", "SHA3-512", "BLAKE2b", "Whirlpool"}},
+}
+
+// ClassifyHash returns the algorithms that likely produced term, most
+// likely first, or nil if term does not look like a hash
+func ClassifyHash(term string) []string {
+	term = strings.TrimSpace(term)
+	for _, format := range hashFormats {
+		if format.match(term) {
+			return append([]string(nil), format.algorithms...)
+		}
+	}
+	return nil
+}
+
+func (d *Detector) isHash(term string) bool {
+	return ClassifyHash(term) != nil
+}
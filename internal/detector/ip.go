@@ -0,0 +1,119 @@
+package detector
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+func (d *Detector) isIP(term string) bool {
+	_, ok := ParseIP(term)
+	return ok
+}
+
+func (d *Detector) isCIDR(term string) bool {
+	_, err := netip.ParsePrefix(term)
+	return err == nil
+}
+
+// ParseIP parses an IPv4 or IPv6 address, accepting IPv6 zone IDs
+// ("fe80::1%eth0") and bracketed forms with an optional port
+// ("[2001:db8::1]" or "[2001:db8::1]:443"). The returned address has its
+// zone removed and is in canonical form.
+func ParseIP(term string) (netip.Addr, bool) {
+	if strings.HasPrefix(term, "[") {
+		host := strings.TrimSuffix(strings.TrimPrefix(term, "["), "]")
+		if h, _, err := net.SplitHostPort(term); err == nil {
+			host = h
+		} else if !strings.HasSuffix(term, "]") {
+			return netip.Addr{}, false
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !addr.Is6() {
+			return netip.Addr{}, false
+		}
+		return addr.WithZone(""), true
+	}
+
+	addr, err := netip.ParseAddr(term)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.WithZone(""), true
+}
+
+// CIDRSize returns the number of addresses in a CIDR range
+func CIDRSize(prefix netip.Prefix) *big.Int {
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	return new(big.Int).Lsh(big.NewInt(1), uint(hostBits))
+}
+
+// ExpandCIDR lists every address in a CIDR range. It refuses ranges holding
+// more than limit addresses.
+func ExpandCIDR(cidr string, limit int) ([]string, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR range %q", cidr)
+	}
+	prefix = prefix.Masked()
+
+	if CIDRSize(prefix).Cmp(big.NewInt(int64(limit))) > 0 {
+		return nil, fmt.Errorf("%s holds %s addresses, more than the limit of %d", cidr, CIDRSize(prefix), limit)
+	}
+
+	var addrs []string
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		addrs = append(addrs, addr.String())
+		if !addr.Next().IsValid() {
+			break
+		}
+	}
+	return addrs, nil
+}
+
+// CIDRWildcards converts an IPv4 CIDR range into wildcard terms that match
+// exactly the addresses in the range, such as "10.1.*" for 10.1.0.0/16.
+// Ranges that do not end on an octet boundary become one term per block of
+// the next boundary (10.0.0.0/20 becomes "10.0.0.*" to "10.0.15.*"). It
+// refuses ranges needing more than limit terms.
+func CIDRWildcards(cidr string, limit int) ([]string, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR range %q", cidr)
+	}
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("wildcards are only supported for IPv4 ranges, not %s", cidr)
+	}
+	prefix = prefix.Masked()
+
+	// Round the prefix up to a whole number of octets
+	fixedOctets := (prefix.Bits() + 7) / 8
+	blocks := 1 << (fixedOctets*8 - prefix.Bits())
+	if blocks > limit {
+		return nil, fmt.Errorf("%s needs %d wildcard terms, more than the limit of %d", cidr, blocks, limit)
+	}
+
+	base := prefix.Addr().As4()
+	step := uint32(1) << (32 - fixedOctets*8)
+	start := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+
+	terms := make([]string, 0, blocks)
+	for i := 0; i < blocks; i++ {
+		n := start + uint32(i)*step
+		octets := []string{
+			fmt.Sprint(n >> 24), fmt.Sprint(n >> 16 & 0xff), fmt.Sprint(n >> 8 & 0xff), fmt.Sprint(n & 0xff),
+		}
+		if fixedOctets == 0 {
+			terms = append(terms, "*")
+			continue
+		}
+		if fixedOctets == 4 {
+			terms = append(terms, strings.Join(octets, "."))
+			continue
+		}
+		terms = append(terms, strings.Join(octets[:fixedOctets], ".")+".*")
+	}
+	return terms, nil
+}
@@ -0,0 +1,121 @@
+package detector
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Normalized is a term prepared for detection and searching
+type Normalized struct {
+	Input    string // The term as given
+	Term     string // The term to detect and search for
+	Unicode  string // Term with its hostname in Unicode, set for IDNs only
+	Refanged bool   // Whether defanged notation was removed
+}
+
+// Changed reports whether normalization altered the input
+func (n Normalized) Changed() bool {
+	return n.Term != n.Input
+}
+
+// defangedScheme matches the scheme spellings used to defang URLs, such as
+// hxxps:// and fxp://
+var defangedScheme = regexp.MustCompile(`(?i)^(hxxps?|hxps?|h\*\*ps?|h\[tt\]ps?|fxps?)(\[:\]|:)`)
+
+// defangedTokens maps bracketed notation to the character it hides. Keys are
+// matched case-insensitively.
+var defangedTokens = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\[:\]//|\[://\]`), "://"},
+	{regexp.MustCompile(`(?i)[\[({]\s*(\.|dot)\s*[\])}]|\\\.`), "."},
+	{regexp.MustCompile(`(?i)[\[({]\s*(@|at)\s*[\])}]`), "@"},
+	{regexp.MustCompile(`\[:\]`), ":"},
+	{regexp.MustCompile(`\[/\]`), "/"},
+}
+
+// Refang undoes the defanging used to share indicators safely, such as
+// hxxps://evil[.]com and user[@]corp[.]com
+func Refang(term string) string {
+	if m := defangedScheme.FindStringSubmatch(term); m != nil {
+		lower := strings.ToLower(m[1])
+		scheme := "http"
+		if strings.HasPrefix(lower, "f") {
+			scheme = "ftp"
+		}
+		if strings.HasSuffix(lower, "s") {
+			scheme += "s"
+		}
+		term = scheme + ":" + term[len(m[0]):]
+	}
+
+	for _, token := range defangedTokens {
+		term = token.pattern.ReplaceAllString(term, token.replacement)
+	}
+	return term
+}
+
+// Normalize refangs term and puts the hostname of URLs, email addresses and
+// domains in canonical form: lowercased, without a trailing dot and with
+// internationalized labels in Punycode. Other terms are only trimmed.
+func Normalize(term string) Normalized {
+	n := Normalized{Input: term}
+
+	normalized := strings.TrimSpace(term)
+	if refanged := Refang(normalized); refanged != normalized {
+		normalized = refanged
+		n.Refanged = true
+	}
+
+	prefix, host, suffix := splitTermHost(normalized)
+	if ascii, ok := canonicalHost(host); ok {
+		normalized = prefix + ascii + suffix
+		if unicode := ToUnicode(ascii); unicode != ascii {
+			n.Unicode = prefix + unicode + suffix
+		}
+	}
+
+	n.Term = normalized
+	return n
+}
+
+// splitTermHost finds the hostname in a URL, an email address or a domain
+// with an optional path, returning the text around it
+func splitTermHost(term string) (string, string, string) {
+	start := 0
+	if i := strings.Index(term, "://"); i > 0 {
+		// Schemes are case-insensitive, so they are lowercased too
+		term = strings.ToLower(term[:i]) + term[i:]
+		start = i + 3
+	}
+
+	end := len(term)
+	if i := strings.IndexAny(term[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	// Skip the local part of email addresses and the user info of URLs
+	if at := strings.LastIndex(term[start:end], "@"); at >= 0 {
+		start += at + 1
+	}
+	// Ports stay outside the host; IPv6 literals are left alone
+	if i := strings.LastIndex(term[start:end], ":"); i >= 0 && !strings.Contains(term[start:end], "]") {
+		end = start + i
+	}
+
+	return term[:start], term[start:end], term[end:]
+}
+
+// canonicalHost returns the ASCII form of host, reporting false when host is
+// not a hostname with at least one dot
+func canonicalHost(host string) (string, bool) {
+	host = strings.TrimRight(idnDots.Replace(host), ".")
+	if !strings.Contains(host, ".") {
+		return "", false
+	}
+	ascii, err := ToASCII(host)
+	if err != nil || !isHostname(ascii) {
+		return "", false
+	}
+	return ascii, true
+}
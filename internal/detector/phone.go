@@ -0,0 +1,86 @@
+package detector
+
+import (
+	"regexp"
+	"strings"
+)
+
+// E.164 numbers hold at most 15 digits; shorter than 7 is more likely an ID
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+// phonePattern allows digits with the separators people type between them
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ().\-]*[0-9]$|^\+?\([0-9]+\)[0-9 ().\-]*[0-9]$`)
+
+// datePattern rules out ISO dates, which look like numbers with dashes
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+func (d *Detector) isPhone(term string) bool {
+	if !phonePattern.MatchString(term) || datePattern.MatchString(term) {
+		return false
+	}
+	n := len(phoneDigits(term))
+	return n >= minPhoneDigits && n <= maxPhoneDigits
+}
+
+// phoneDigits strips everything but digits
+func phoneDigits(term string) string {
+	var b strings.Builder
+	for _, c := range term {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// NormalizePhone converts a phone number to E.164 ("+15551234567").
+// Numbers starting with "+" or the international prefix "00" keep their
+// country code; national numbers get countryCode, dropping a leading trunk
+// "0" (or "1" for 11-digit North American numbers).
+func NormalizePhone(term, countryCode string) (string, bool) {
+	if !new(Detector).isPhone(term) {
+		return "", false
+	}
+	digits := phoneDigits(term)
+	countryCode = strings.TrimPrefix(strings.TrimSpace(countryCode), "+")
+
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(term), "+"):
+		return "+" + digits, true
+	case strings.HasPrefix(digits, "00"):
+		return "+" + digits[2:], true
+	case countryCode == "":
+		return "", false
+	case countryCode == "1" && len(digits) == 11 && digits[0] == '1':
+		return "+" + digits, true
+	case strings.HasPrefix(digits, "0"):
+		digits = digits[1:]
+	}
+
+	e164 := "+" + countryCode + digits
+	if len(e164)-1 > maxPhoneDigits {
+		return "", false
+	}
+	return e164, true
+}
+
+// PhoneVariants returns the forms a number is commonly stored in: E.164,
+// E.164 without the plus and, when the country code matches countryCode,
+// the national form (with a trunk "0" outside North America)
+func PhoneVariants(e164, countryCode string) []string {
+	digits := strings.TrimPrefix(e164, "+")
+	variants := []string{e164, digits}
+
+	countryCode = strings.TrimPrefix(strings.TrimSpace(countryCode), "+")
+	if countryCode != "" && strings.HasPrefix(digits, countryCode) {
+		national := digits[len(countryCode):]
+		if countryCode != "1" {
+			national = "0" + national
+		}
+		variants = append(variants, national)
+	}
+	return variants
+}
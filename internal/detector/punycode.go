@@ -0,0 +1,234 @@
+package detector
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Punycode parameters from RFC 3492, section 5
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// acePrefix marks a label encoded with Punycode
+const acePrefix = "xn--"
+
+var errPunycode = errors.New("invalid punycode")
+
+// punyAdapt is the bias adaptation function of RFC 3492, section 6.1
+func punyAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+// punyThreshold clamps k - bias to [tmin, tmax]
+func punyThreshold(k, bias int) int {
+	switch {
+	case k <= bias:
+		return punyTMin
+	case k >= bias+punyTMax:
+		return punyTMax
+	}
+	return k - bias
+}
+
+func punyEncodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+func punyDecodeDigit(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26, true
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	}
+	return 0, false
+}
+
+// punyEncode encodes a label with Punycode, without the xn-- prefix
+func punyEncode(label string) (string, error) {
+	input := []rune(label)
+	var out []byte
+	for _, r := range input {
+		if r < utf8.RuneSelf {
+			out = append(out, byte(r))
+		}
+	}
+
+	basic := len(out)
+	handled := basic
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := punyInitialN, 0, punyInitialBias
+	for handled < len(input) {
+		// The smallest code point not handled yet
+		m := math.MaxInt32
+		for _, r := range input {
+			if int(r) >= n && int(r) < m {
+				m = int(r)
+			}
+		}
+		if (m - n) > (math.MaxInt32-delta)/(handled+1) {
+			return "", errPunycode
+		}
+		delta += (m - n) * (handled + 1)
+		n = m
+
+		for _, r := range input {
+			if int(r) < n {
+				delta++
+			}
+			if int(r) != n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := punyThreshold(k, bias)
+				if q < t {
+					break
+				}
+				out = append(out, punyEncodeDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			out = append(out, punyEncodeDigit(q))
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+
+	return string(out), nil
+}
+
+// punyDecode decodes a Punycode label given without the xn-- prefix
+func punyDecode(encoded string) (string, error) {
+	var output []rune
+	pos := 0
+	if b := strings.LastIndexByte(encoded, '-'); b >= 0 {
+		for i := 0; i < b; i++ {
+			if encoded[i] >= utf8.RuneSelf {
+				return "", errPunycode
+			}
+			output = append(output, rune(encoded[i]))
+		}
+		pos = b + 1
+	}
+
+	n, i, bias := punyInitialN, 0, punyInitialBias
+	for pos < len(encoded) {
+		oldi, w := i, 1
+		for k := punyBase; ; k += punyBase {
+			if pos == len(encoded) {
+				return "", errPunycode
+			}
+			digit, ok := punyDecodeDigit(encoded[pos])
+			pos++
+			if !ok || digit > (math.MaxInt32-i)/w {
+				return "", errPunycode
+			}
+			i += digit * w
+			t := punyThreshold(k, bias)
+			if digit < t {
+				break
+			}
+			if w > math.MaxInt32/(punyBase-t) {
+				return "", errPunycode
+			}
+			w *= punyBase - t
+		}
+
+		bias = punyAdapt(i-oldi, len(output)+1, oldi == 0)
+		n += i / (len(output) + 1)
+		if n > utf8.MaxRune {
+			return "", errPunycode
+		}
+		i %= len(output) + 1
+
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = rune(n)
+		i++
+	}
+
+	return string(output), nil
+}
+
+// ToASCII converts a hostname to its ASCII form: labels are lowercased and
+// labels with non-ASCII characters are Punycode-encoded with the xn--
+// prefix. Unlike full IDNA, no other mapping or validation is applied.
+func ToASCII(host string) (string, error) {
+	labels := splitHost(host)
+	for i, label := range labels {
+		label = strings.ToLower(label)
+		if isASCII(label) {
+			labels[i] = label
+			continue
+		}
+		encoded, err := punyEncode(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = acePrefix + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// ToUnicode converts the xn-- labels of a hostname back to Unicode. Labels
+// that are not valid Punycode are kept as they are.
+func ToUnicode(host string) string {
+	labels := splitHost(host)
+	for i, label := range labels {
+		if len(label) > len(acePrefix) && strings.EqualFold(label[:len(acePrefix)], acePrefix) {
+			if decoded, err := punyDecode(label[len(acePrefix):]); err == nil {
+				labels[i] = decoded
+			}
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// idnDots replaces the ideographic and fullwidth full stops IDNs are often
+// typed with by ASCII dots
+var idnDots = strings.NewReplacer("。", ".", "．", ".", "｡", ".")
+
+func splitHost(host string) []string {
+	return strings.Split(idnDots.Replace(host), ".")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
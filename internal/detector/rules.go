@@ -0,0 +1,159 @@
+package detector
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// Candidate is a type a term may belong to, as proposed by a Rule
+type Candidate struct {
+	Type       string  // Search type, such as "email" or "hash"
+	Confidence float64 // How likely the type is, from 0 to 1
+	Rule       string  // Name of the rule that proposed the type
+	Detail     string  // Optional explanation, such as the likely hash algorithms
+}
+
+// Rule proposes candidate types for a single term
+type Rule interface {
+	Name() string
+	Detect(term string) []Candidate
+}
+
+// RuleFunc adapts a function to the Rule interface
+type RuleFunc struct {
+	RuleName string
+	Func     func(term string) []Candidate
+}
+
+func (r RuleFunc) Name() string {
+	return r.RuleName
+}
+
+func (r RuleFunc) Detect(term string) []Candidate {
+	candidates := r.Func(term)
+	for i := range candidates {
+		candidates[i].Rule = r.RuleName
+	}
+	return candidates
+}
+
+// RegexRule proposes a fixed type for terms matching a regular expression,
+// for formats only a team knows about, such as internal usernames
+type RegexRule struct {
+	name       string
+	typ        string
+	pattern    *regexp.Regexp
+	confidence float64
+}
+
+// NewRegexRule compiles a regex rule. The pattern is anchored at both ends,
+// so it must match the whole term.
+func NewRegexRule(name, typ, pattern string, confidence float64) (*RegexRule, error) {
+	if typ == "" {
+		return nil, fmt.Errorf("rule %q has no type", name)
+	}
+	if confidence <= 0 || confidence > 1 {
+		return nil, fmt.Errorf("rule %q: confidence must be in (0, 1], not %v", name, confidence)
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: invalid pattern: %v", name, err)
+	}
+	return &RegexRule{name: name, typ: typ, pattern: re, confidence: confidence}, nil
+}
+
+func (r *RegexRule) Name() string {
+	return r.name
+}
+
+func (r *RegexRule) Detect(term string) []Candidate {
+	if !r.pattern.MatchString(term) {
+		return nil
+	}
+	return []Candidate{{Type: r.typ, Confidence: r.confidence, Rule: r.name}}
+}
+
+// candidate is shorthand for rules proposing a single type
+func candidate(typ string, confidence float64, detail string) []Candidate {
+	return []Candidate{{Type: typ, Confidence: confidence, Detail: detail}}
+}
+
+// defaultRules are the built-in rules, registered by New
+func (d *Detector) defaultRules() []Rule {
+	return []Rule{
+		RuleFunc{"uuid", func(term string) []Candidate {
+			if d.isUUID(term) {
+				return candidate("uuid", 0.99, "")
+			}
+			return nil
+		}},
+		RuleFunc{"hash", func(term string) []Candidate {
+			algorithms := ClassifyHash(term)
+			if algorithms == nil {
+				return nil
+			}
+			// Bare hex digests could also be tokens or IDs
+			confidence := 0.95
+			if d.isHex(term) {
+				confidence = 0.8
+			}
+			return candidate("hash", confidence, "likely "+strings.Join(algorithms, ", "))
+		}},
+		RuleFunc{"ip", func(term string) []Candidate {
+			if addr, ok := ParseIP(term); ok {
+				return candidate("ip", 0.99, addr.String())
+			}
+			if prefix, err := netip.ParsePrefix(term); err == nil {
+				return candidate("ip", 0.95, fmt.Sprintf("CIDR range of %s addresses", CIDRSize(prefix.Masked())))
+			}
+			return nil
+		}},
+		RuleFunc{"phone", func(term string) []Candidate {
+			if !d.isPhone(term) {
+				return nil
+			}
+			// Formatting makes a number far more likely to be a phone number
+			confidence := 0.5
+			if strings.ContainsAny(term, "+ ()-.") {
+				confidence = 0.75
+			}
+			detail := ""
+			if e164, ok := NormalizePhone(term, ""); ok {
+				detail = e164
+			}
+			return candidate("phone", confidence, detail)
+		}},
+		RuleFunc{"email", func(term string) []Candidate {
+			if d.isEmail(term) {
+				return candidate("email", 0.95, "")
+			}
+			return nil
+		}},
+		RuleFunc{"url", func(term string) []Candidate {
+			switch {
+			case d.isURL(term):
+				return candidate("url", 0.95, "")
+			case d.isHostWithPath(term):
+				return candidate("url", 0.8, "host with a path")
+			case d.isDomain(term):
+				// A bare host may also appear in stored URLs
+				return candidate("url", 0.3, "bare host")
+			}
+			return nil
+		}},
+		RuleFunc{"domain", func(term string) []Candidate {
+			if d.isDomain(term) {
+				return candidate("domain", 0.8, "")
+			}
+			return nil
+		}},
+	}
+}
+
+// isHostWithPath matches URLs typed without a scheme, like example.com/login
+func (d *Detector) isHostWithPath(term string) bool {
+	slash := strings.Index(term, "/")
+	return slash > 0 && !strings.Contains(term, "://") && d.isDomain(term[:slash])
+}
@@ -0,0 +1,232 @@
+// Package filter implements the expression language used by --filter to
+// select search result records client-side.
+//
+// An expression compares record fields with values and combines the
+// comparisons with boolean operators:
+//
+//	url =~ "login|signin" and not (domain == example.com or domain == example.org)
+//	page >= 2 && log.uuid != null
+//
+// Fields are named by their flattened keys, with dots for nested objects.
+// Supported comparisons are ==, !=, <, <=, >, >=, =~ (regular expression
+// match) and !~. A field on its own tests that it is present and not empty.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed filter expression
+type Expr interface {
+	// Match reports whether record satisfies the expression
+	Match(record map[string]interface{}) bool
+}
+
+// Parse compiles a filter expression
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &SyntaxError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return expr, nil
+}
+
+type andExpr struct{ left, right Expr }
+
+func (e andExpr) Match(record map[string]interface{}) bool {
+	return e.left.Match(record) && e.right.Match(record)
+}
+
+type orExpr struct{ left, right Expr }
+
+func (e orExpr) Match(record map[string]interface{}) bool {
+	return e.left.Match(record) || e.right.Match(record)
+}
+
+type notExpr struct{ operand Expr }
+
+func (e notExpr) Match(record map[string]interface{}) bool {
+	return !e.operand.Match(record)
+}
+
+// existsExpr matches records where field is set to a non-empty value
+type existsExpr struct{ field string }
+
+func (e existsExpr) Match(record map[string]interface{}) bool {
+	value, ok := Lookup(record, e.field)
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return v != ""
+	case bool:
+		return v
+	}
+	return true
+}
+
+type compareOp int
+
+const (
+	opEq compareOp = iota
+	opNe
+	opLt
+	opLe
+	opGt
+	opGe
+)
+
+// compareExpr compares a field with a literal. Values are compared as
+// numbers when both sides are numeric and as strings otherwise.
+type compareExpr struct {
+	field string
+	op    compareOp
+	value interface{}
+}
+
+func (e compareExpr) Match(record map[string]interface{}) bool {
+	actual, ok := Lookup(record, e.field)
+	if !ok {
+		actual = nil
+	}
+
+	// null only equals a missing or null field
+	if e.value == nil || actual == nil {
+		equal := e.value == nil && actual == nil
+		switch e.op {
+		case opEq:
+			return equal
+		case opNe:
+			return !equal
+		}
+		return false
+	}
+
+	var cmp int
+	if a, ok := number(actual); ok {
+		if b, ok := number(e.value); ok {
+			cmp = compareFloats(a, b)
+			return e.op.holds(cmp)
+		}
+	}
+	cmp = strings.Compare(String(actual), String(e.value))
+	return e.op.holds(cmp)
+}
+
+func (op compareOp) holds(cmp int) bool {
+	switch op {
+	case opEq:
+		return cmp == 0
+	case opNe:
+		return cmp != 0
+	case opLt:
+		return cmp < 0
+	case opLe:
+		return cmp <= 0
+	case opGt:
+		return cmp > 0
+	case opGe:
+		return cmp >= 0
+	}
+	return false
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// matchExpr matches a field against a regular expression
+type matchExpr struct {
+	field  string
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (e matchExpr) Match(record map[string]interface{}) bool {
+	value, ok := Lookup(record, e.field)
+	matched := ok && value != nil && e.re.MatchString(String(value))
+	return matched != e.negate
+}
+
+// Lookup finds field in record, either as a flattened dotted key or by
+// walking nested objects
+func Lookup(record map[string]interface{}, field string) (interface{}, bool) {
+	if value, ok := record[field]; ok {
+		return value, true
+	}
+
+	var current interface{} = record
+	for _, part := range strings.Split(field, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// String renders a record value the way comparisons and regular expressions see it
+func String(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
+
+// number converts numeric values, including numeric strings, to float64
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
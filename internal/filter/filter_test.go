@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestParse_Match(t *testing.T) {
+	record := map[string]interface{}{
+		"group":    "email",
+		"url":      "https://login.example.com/signin",
+		"login":    "admin@example.com",
+		"page":     float64(2),
+		"size":     "1024",
+		"empty":    "",
+		"verified": true,
+		"log":      map[string]interface{}{"uuid": "abc-123"},
+		"log.date": "2024-01-02",
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`group == email`, true},
+		{`group == "email"`, true},
+		{`group = 'email'`, true},
+		{`group != email`, false},
+		{`page == 2`, true},
+		{`page > 1 and page < 3`, true},
+		{`page >= 3`, false},
+		{`size > 999`, true},
+		{`size <= 1024`, true},
+		{`login > "a"`, true},
+		{`url =~ "login|signin"`, true},
+		{`url =~ "^http://"`, false},
+		{`url !~ "example\.org"`, true},
+		{`login =~ "(?i)ADMIN@"`, true},
+		{`log.uuid == abc-123`, true},
+		{`log.date =~ "^\d{4}-"`, true},
+		{`missing == null`, true},
+		{`missing != null`, false},
+		{`login != null`, true},
+		{`missing =~ "x"`, false},
+		{`missing !~ "x"`, true},
+		{`verified == true`, true},
+		{`verified`, true},
+		{`empty`, false},
+		{`missing`, false},
+		{`not missing`, true},
+		{`!verified`, false},
+		{`group == url or page == 2`, true},
+		{`group == url || page == 3`, false},
+		{`group == email && not (url =~ "example\.com" or page == 1)`, false},
+		{`NOT group == url AND page == 2`, true},
+		{`group == url or group == email and page == 3`, false},
+		{`(group == url or group == email) and page == 2`, true},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", test.expr, err)
+			continue
+		}
+		if result := expr.Match(record); result != test.expected {
+			t.Errorf("Parse(%q).Match() = %v, expected %v", test.expr, result, test.expected)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		``,
+		`group ==`,
+		`group == email and`,
+		`(group == email`,
+		`group == email)`,
+		`url =~ "[unclosed"`,
+		`url =~ 5`,
+		`group == "unterminated`,
+		`group # email`,
+		`and == x`,
+		`== email`,
+		`group == email email`,
+	}
+
+	for _, input := range tests {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, expected an error", input)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("Parse(%q) returned %T, expected *SyntaxError", input, err)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	record := map[string]interface{}{
+		"a.b": 1,
+		"c":   map[string]interface{}{"d": map[string]interface{}{"e": "deep"}},
+	}
+
+	if v, ok := Lookup(record, "a.b"); !ok || v != 1 {
+		t.Errorf("Lookup(a.b) = %v, %v, expected the flattened key", v, ok)
+	}
+	if v, ok := Lookup(record, "c.d.e"); !ok || v != "deep" {
+		t.Errorf("Lookup(c.d.e) = %v, %v, expected the nested value", v, ok)
+	}
+	if _, ok := Lookup(record, "c.x"); ok {
+		t.Error("Lookup(c.x) found a missing field")
+	}
+}
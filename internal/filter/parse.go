@@ -0,0 +1,308 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError reports an invalid filter expression
+type SyntaxError struct {
+	Pos int    // Byte offset of the offending token
+	Msg string // What was wrong
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Pos+1, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits input into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '"' || c == '\'':
+			text, n, err := lexString(input[i:])
+			if err != nil {
+				return nil, &SyntaxError{i, err.Error()}
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i += n
+		case strings.ContainsRune("=!<>&|", rune(c)):
+			op := lexOp(input[i:])
+			if op == "" {
+				return nil, &SyntaxError{i, fmt.Sprintf("unexpected %q", c)}
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(input) && isIdentPart(rune(input[i])) {
+				i++
+			}
+			// Words like 10.0.0.1 that start like a number are bare strings
+			kind := tokNumber
+			if _, err := strconv.ParseFloat(input[start:i], 64); err != nil {
+				kind = tokIdent
+			}
+			tokens = append(tokens, token{kind, input[start:i], start})
+		case isIdentStart(rune(c)):
+			start := i
+			for i < len(input) && isIdentPart(rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, input[start:i], start})
+		default:
+			return nil, &SyntaxError{i, fmt.Sprintf("unexpected %q", c)}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(input)}), nil
+}
+
+// lexString reads a quoted string, returning its value and length in input
+func lexString(input string) (string, int, error) {
+	quote := input[0]
+	var b strings.Builder
+	for i := 1; i < len(input); i++ {
+		switch c := input[i]; c {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(input) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			i++
+			// Keep escapes other than quotes and backslashes, so regular
+			// expressions like "\d+" can be written naturally
+			if next := input[i]; next != quote && next != '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(input[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// operators lists the recognized operators, longest first
+var operators = []string{"==", "!=", "<=", ">=", "=~", "!~", "&&", "||", "<", ">", "!", "="}
+
+func lexOp(input string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(input, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || r == '.' || r == '-'
+}
+
+// parser is a recursive descent parser over the token list:
+//
+//	or         = and { ("or" | "||") and }
+//	and        = unary { ("and" | "&&") unary }
+//	unary      = ("not" | "!") unary | primary
+//	primary    = "(" or ")" | field [ op value ]
+//	op         = "==" | "=" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "!~"
+//	value      = string | number | "true" | "false" | "null" | word
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether tok is one of the given operators or keywords.
+// Keywords are case-insensitive.
+func keyword(tok token, words ...string) bool {
+	if tok.kind != tokOp && tok.kind != tokIdent {
+		return false
+	}
+	for _, word := range words {
+		if strings.EqualFold(tok.text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for keyword(p.peek(), "or", "||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for keyword(p.peek(), "and", "&&") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if keyword(p.peek(), "not", "!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &SyntaxError{closing.pos, "expected )"}
+		}
+		return expr, nil
+	case tokIdent:
+		if keyword(tok, "and", "or", "not") {
+			return nil, &SyntaxError{tok.pos, fmt.Sprintf("expected a field, found %q", tok.text)}
+		}
+	case tokEOF:
+		return nil, &SyntaxError{tok.pos, "unexpected end of expression"}
+	default:
+		return nil, &SyntaxError{tok.pos, fmt.Sprintf("expected a field, found %q", tok.text)}
+	}
+
+	field := tok.text
+	op := p.peek()
+	if op.kind != tokOp || keyword(op, "&&", "||", "!") {
+		return existsExpr{field}, nil
+	}
+	p.next()
+
+	valueTok := p.next()
+	value, err := literal(valueTok)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.text {
+	case "==", "=":
+		return compareExpr{field, opEq, value}, nil
+	case "!=":
+		return compareExpr{field, opNe, value}, nil
+	case "<":
+		return compareExpr{field, opLt, value}, nil
+	case "<=":
+		return compareExpr{field, opLe, value}, nil
+	case ">":
+		return compareExpr{field, opGt, value}, nil
+	case ">=":
+		return compareExpr{field, opGe, value}, nil
+	case "=~", "!~":
+		pattern, ok := value.(string)
+		if !ok {
+			return nil, &SyntaxError{valueTok.pos, "regular expressions must be strings"}
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, &SyntaxError{valueTok.pos, fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+		return matchExpr{field, re, op.text == "!~"}, nil
+	}
+	return nil, &SyntaxError{op.pos, fmt.Sprintf("unexpected %q", op.text)}
+}
+
+// literal converts a value token into a string, float64, bool or nil.
+// Bare words are read as strings, so quotes are optional for simple values.
+func literal(tok token) (interface{}, error) {
+	switch tok.kind {
+	case tokString:
+		return tok.text, nil
+	case tokNumber:
+		n, _ := strconv.ParseFloat(tok.text, 64)
+		return n, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return tok.text, nil
+	case tokEOF:
+		return nil, &SyntaxError{tok.pos, "expected a value, found end of expression"}
+	}
+	return nil, &SyntaxError{tok.pos, fmt.Sprintf("expected a value, found %q", tok.text)}
+}
@@ -0,0 +1,266 @@
+// Package jobs persists the state of batch runs so an interrupted batch can
+// be resumed where it stopped.
+//
+// Each job is a JSON manifest in the jobs directory holding the command it
+// runs and the completion state of every term. The manifest is rewritten
+// after every request, so at most the requests in flight are lost.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Job statuses
+const (
+	StatusRunning     = "running"
+	StatusInterrupted = "interrupted"
+	StatusCompleted   = "completed"
+	StatusCancelled   = "cancelled"
+)
+
+// Term statuses
+const (
+	TermPending = "pending"
+	TermFound   = "found"
+	TermNone    = "none"
+	TermFailed  = "failed"
+	TermSkipped = "skipped"
+)
+
+// ErrNotFound is returned for job IDs without a manifest
+var ErrNotFound = errors.New("job not found")
+
+// ErrCancelled is returned when saving a job that was cancelled meanwhile
+var ErrCancelled = errors.New("job was cancelled")
+
+// Job is the manifest of one batch run
+type Job struct {
+	ID      string    `json:"id"`
+	Command string    `json:"command"`
+	Args    []string  `json:"args"`
+	Status  string    `json:"status"`
+	Dir     string    `json:"dir"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Error   string    `json:"error,omitempty"`
+	Terms   []Term    `json:"terms"`
+}
+
+// Term is the state of one term of a job
+type Term struct {
+	Term    string   `json:"term"`
+	Input   string   `json:"input,omitempty"`
+	Types   []string `json:"types,omitempty"`
+	Status  string   `json:"status"`
+	Results int64    `json:"results"`
+	Error   string   `json:"error,omitempty"`
+	File    string   `json:"file,omitempty"`
+}
+
+// Remaining reports whether the term still has to be run: it is pending or
+// its request failed
+func (t Term) Remaining() bool {
+	return t.Status == TermPending || t.Status == TermFailed
+}
+
+// Counts returns the number of terms in each term status
+func (j *Job) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, term := range j.Terms {
+		counts[term.Status]++
+	}
+	return counts
+}
+
+// Remaining returns the number of terms still to be run
+func (j *Job) Remaining() int {
+	n := 0
+	for _, term := range j.Terms {
+		if term.Remaining() {
+			n++
+		}
+	}
+	return n
+}
+
+// Resumable reports whether the job can be resumed
+func (j *Job) Resumable() bool {
+	return j.Status != StatusCompleted && j.Status != StatusCancelled && j.Remaining() > 0
+}
+
+// Store keeps job manifests in a directory
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store keeping manifests in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultStore returns the store in ~/.keyscore-cli/jobs
+func DefaultStore() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %v", err)
+	}
+	return NewStore(filepath.Join(homeDir, ".keyscore-cli", "jobs")), nil
+}
+
+// Dir returns the directory holding the manifests
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Create saves a new running job
+func (s *Store) Create(command string, args []string, dir string, terms []Term) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	job := &Job{
+		ID:      id,
+		Command: command,
+		Args:    args,
+		Status:  StatusRunning,
+		Dir:     dir,
+		Created: now,
+		Updated: now,
+		Terms:   terms,
+	}
+	if err := s.write(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Load reads the manifest of job id
+func (s *Store) Load(id string) (*Job, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading job %s: %v", id, err)
+	}
+
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("error reading job %s: %v", id, err)
+	}
+	return &job, nil
+}
+
+// List returns every job, newest first
+func (s *Store) List() ([]*Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading jobs directory: %v", err)
+	}
+
+	var list []*Job
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		if entry.IsDir() || id == entry.Name() {
+			continue
+		}
+		job, err := s.Load(id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, job)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.After(list[j].Created)
+	})
+	return list, nil
+}
+
+// Save checkpoints a job. A job cancelled by another process since it was
+// loaded stays cancelled, and ErrCancelled is returned so the run can stop.
+func (s *Store) Save(job *Job) error {
+	if onDisk, err := s.Load(job.ID); err == nil && onDisk.Status == StatusCancelled && job.Status != StatusCancelled {
+		job.Status = StatusCancelled
+		if err := s.write(job); err != nil {
+			return err
+		}
+		return ErrCancelled
+	}
+	return s.write(job)
+}
+
+// Cancel marks a job as cancelled so it is never resumed. A run in progress
+// stops at its next checkpoint.
+func (s *Store) Cancel(id string) (*Job, error) {
+	job, err := s.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == StatusCompleted {
+		return nil, fmt.Errorf("job %s has already completed", id)
+	}
+	job.Status = StatusCancelled
+	return job, s.write(job)
+}
+
+// write replaces the manifest atomically, so a crash never leaves a
+// truncated file behind
+func (s *Store) write(job *Job) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create jobs directory: %v", err)
+	}
+
+	job.Updated = time.Now()
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, job.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write job: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write job: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write job: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(job.ID)); err != nil {
+		return fmt.Errorf("failed to write job: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// newID returns a sortable, unique job ID such as 20240102-150405-9f3a
+func newID() (string, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to create job ID: %v", err)
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
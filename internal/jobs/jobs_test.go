@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_CreateLoadList(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	first, err := store.Create("search", []string{"-input", "terms.txt"}, "/results/search-batch", []Term{
+		{Term: "alice@corp.com", Types: []string{"login"}, Status: TermPending},
+		{Term: "hello", Status: TermSkipped},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	second, err := store.Create("count", nil, "/results/count-batch", nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("jobs share the ID %s", first.ID)
+	}
+
+	loaded, err := store.Load(first.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Command != "search" || loaded.Status != StatusRunning || len(loaded.Terms) != 2 || loaded.Remaining() != 1 {
+		t.Errorf("Load = %+v, expected the running search job with one remaining term", loaded)
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("List returned %d jobs, expected 2", len(list))
+	}
+
+	// Leftover temporary files are not jobs
+	if err := os.WriteFile(filepath.Join(store.Dir(), "x.json.tmp"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := store.List(); err != nil || len(list) != 2 {
+		t.Errorf("List = %d jobs, %v after adding a temporary file", len(list), err)
+	}
+}
+
+func TestStore_LoadMissing(t *testing.T) {
+	store := NewStore(t.TempDir())
+	for _, id := range []string{"missing", "", "../config"} {
+		if _, err := store.Load(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Load(%q) error = %v, expected ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestStore_CancelStopsRunningJob(t *testing.T) {
+	store := NewStore(t.TempDir())
+	job, err := store.Create("search", nil, "", []Term{{Term: "a", Status: TermPending}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := store.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	// The running process still holds its own copy of the job
+	job.Terms[0].Status = TermFound
+	if err := store.Save(job); !errors.Is(err, ErrCancelled) {
+		t.Fatalf("Save error = %v, expected ErrCancelled", err)
+	}
+
+	loaded, err := store.Load(job.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Status != StatusCancelled || loaded.Terms[0].Status != TermFound {
+		t.Errorf("Load = %+v, expected a cancelled job keeping its progress", loaded)
+	}
+	if loaded.Resumable() {
+		t.Error("cancelled job is resumable")
+	}
+}
+
+func TestStore_CancelCompleted(t *testing.T) {
+	store := NewStore(t.TempDir())
+	job, err := store.Create("count", nil, "", nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	job.Status = StatusCompleted
+	if err := store.Save(job); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if _, err := store.Cancel(job.ID); err == nil {
+		t.Error("Cancel accepted a completed job")
+	}
+}
@@ -2,6 +2,8 @@ package spinner
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -154,26 +156,49 @@ func WithText(message string) *Spinner {
 
 type ProgressBar struct {
 	message    string
-	total      int
-	current    int
+	total      int64
+	current    int64
 	width      int
 	percentage int
 	running    bool
 	stopChan   chan struct{}
 	doneChan   chan struct{}
 	wg         sync.WaitGroup
+
+	mu      sync.Mutex
+	out     io.Writer
+	bytes   bool      // Counts bytes, with throughput and ETA
+	started time.Time // First byte update
+	base    int64     // Bytes already there at the first update
 }
 
 func NewProgressBar(message string, total int) *ProgressBar {
 	return &ProgressBar{
 		message:  message,
-		total:    total,
+		total:    int64(total),
 		width:    40,
 		stopChan: make(chan struct{}),
 		doneChan: make(chan struct{}),
+		out:      os.Stdout,
 	}
 }
 
+// NewBytesProgressBar returns a progress bar counting bytes, which also shows
+// the throughput and the time left. A total of 0 or less means unknown.
+func NewBytesProgressBar(message string, total int64) *ProgressBar {
+	p := NewProgressBar(message, 0)
+	p.total = total
+	p.bytes = true
+	return p
+}
+
+// SetOutput sets where the bar is drawn; it defaults to stdout
+func (p *ProgressBar) SetOutput(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.out = w
+}
+
 func (p *ProgressBar) Start() {
 	if p.running {
 		return
@@ -189,7 +214,9 @@ func (p *ProgressBar) Start() {
 			select {
 			case <-p.stopChan:
 				p.render()
-				fmt.Println()
+				p.mu.Lock()
+				fmt.Fprintln(p.out)
+				p.mu.Unlock()
 				p.doneChan <- struct{}{}
 				return
 			default:
@@ -201,13 +228,31 @@ func (p *ProgressBar) Start() {
 }
 
 func (p *ProgressBar) Increment() {
-	p.current++
-	if p.total > 0 {
-		p.percentage = int(float64(p.current) / float64(p.total) * 100)
-	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setCurrent(p.current + 1)
 }
 
 func (p *ProgressBar) SetCurrent(current int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setCurrent(int64(current))
+}
+
+// Update sets the progress and the total, which may only become known once
+// the work has started
+func (p *ProgressBar) Update(current, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started.IsZero() {
+		p.started = time.Now()
+		p.base = current
+	}
+	p.total = total
+	p.setCurrent(current)
+}
+
+func (p *ProgressBar) setCurrent(current int64) {
 	p.current = current
 	if p.total > 0 {
 		p.percentage = int(float64(p.current) / float64(p.total) * 100)
@@ -215,16 +260,55 @@ func (p *ProgressBar) SetCurrent(current int) {
 }
 
 func (p *ProgressBar) render() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.bytes {
+		p.renderBytes()
+		return
+	}
+
 	if p.total <= 0 {
 		chars := []string{"=", "===", "====", "=====", "====", "===", "="}
 		char := chars[time.Now().UnixMilli()/200%int64(len(chars))]
-		fmt.Printf("\r%s [%s]", p.message, char)
+		fmt.Fprintf(p.out, "\r%s [%s]", p.message, char)
 		return
 	}
 
+	fmt.Fprintf(p.out, "\r%s [%s] %d/%d (%d%%)", p.message, p.bar(), p.current, p.total, p.percentage)
+}
+
+// renderBytes draws a line such as
+// "Downloading [=====     ] 1.2 MiB / 2.4 MiB (50%) 640.0 KiB/s ETA 2s"
+func (p *ProgressBar) renderBytes() {
+	var rate float64
+	if elapsed := time.Since(p.started).Seconds(); !p.started.IsZero() && elapsed > 0 {
+		rate = float64(p.current-p.base) / elapsed
+	}
+
+	line := p.message + " "
+	if p.total > 0 {
+		line += fmt.Sprintf("[%s] %s / %s (%d%%)", p.bar(), FormatBytes(p.current), FormatBytes(p.total), p.percentage)
+	} else {
+		line += FormatBytes(p.current)
+	}
+	if rate > 0 {
+		line += fmt.Sprintf(" %s/s", FormatBytes(int64(rate)))
+		if p.total > p.current {
+			eta := time.Duration(float64(p.total-p.current) / rate * float64(time.Second))
+			line += fmt.Sprintf(" ETA %s", eta.Round(time.Second))
+		}
+	}
+	// Clear what is left of a longer previous line
+	fmt.Fprintf(p.out, "\r%s\033[K", line)
+}
+
+func (p *ProgressBar) bar() string {
 	filled := int(float64(p.width) * float64(p.current) / float64(p.total))
-	bar := strings.Repeat("=", filled) + strings.Repeat(" ", p.width-filled)
-	fmt.Printf("\r%s [%s] %d/%d (%d%%)", p.message, bar, p.current, p.total, p.percentage)
+	if filled > p.width {
+		filled = p.width
+	}
+	return strings.Repeat("=", filled) + strings.Repeat(" ", p.width-filled)
 }
 
 func (p *ProgressBar) Stop() {
@@ -238,9 +322,27 @@ func (p *ProgressBar) Stop() {
 }
 
 func (p *ProgressBar) SetMessage(message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.message = message
 }
 
+// FormatBytes formats a byte count in binary units, such as 1.5 MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	// Move on once the value would round up to 1024.0, as it does just under
+	// the next unit
+	for value >= unit-0.05 && exp < len("KMGTPE")-1 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGTPE"[exp])
+}
+
 func ShowLoading(message string, duration time.Duration) {
 	spinner := New(message)
 	spinner.Start()
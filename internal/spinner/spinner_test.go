@@ -0,0 +1,48 @@
+package spinner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{1024*1024 - 1, "1.0 MiB"},
+		{1023 * 1024, "1023.0 KiB"},
+		{1024 * 1024, "1.0 MiB"},
+		{5 * 1024 * 1024 * 1024, "5.0 GiB"},
+	}
+
+	for _, test := range tests {
+		if got := FormatBytes(test.n); got != test.expected {
+			t.Errorf("FormatBytes(%d) = %q, expected %q", test.n, got, test.expected)
+		}
+	}
+}
+
+func TestProgressBar_RenderBytesUnknownTotal(t *testing.T) {
+	var out bytes.Buffer
+	p := NewBytesProgressBar("Downloading", -1)
+	p.SetOutput(&out)
+
+	p.Update(2048, -1)
+	p.render()
+
+	line := strings.TrimSuffix(out.String(), "\033[K")
+	if !strings.HasPrefix(line, "\rDownloading 2.0 KiB") {
+		t.Errorf("render = %q, expected the bytes so far", line)
+	}
+	for _, unexpected := range []string{"[", "%", "ETA"} {
+		if strings.Contains(line, unexpected) {
+			t.Errorf("render = %q, expected no %q without a total", line, unexpected)
+		}
+	}
+}
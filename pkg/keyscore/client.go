@@ -0,0 +1,346 @@
+package keyscore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to the keyscore API. It is safe for concurrent use.
+type Client struct {
+	baseURL        string
+	httpClient     *http.Client
+	apiKeys        APIKeyProvider
+	userAgent      string
+	requestTimeout time.Duration
+	retry          RetryPolicy
+	limiter        *rateLimiter
+}
+
+// New returns a client for the public API configured by opts
+func New(opts ...Option) *Client {
+	c := &Client{
+		baseURL:        DefaultBaseURL,
+		apiKeys:        StaticAPIKey(""),
+		userAgent:      "keyscore-go/" + Version,
+		requestTimeout: DefaultRequestTimeout,
+		retry:          DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = newHTTPClient(c.requestTimeout)
+	}
+	return c
+}
+
+// newHTTPClient builds an HTTP client whose transport gives up on servers
+// that do not send response headers within the request timeout. Body reads
+// are bounded by the caller's context instead, so long downloads are not
+// cut off.
+func newHTTPClient(requestTimeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = requestTimeout
+	return &http.Client{Transport: transport}
+}
+
+// resolveAPIKey asks the configured provider for the key to use
+func (c *Client) resolveAPIKey(ctx context.Context) (string, error) {
+	apiKey, err := c.apiKeys.APIKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error getting API key: %w", err)
+	}
+	return apiKey, nil
+}
+
+// requestOptions describes how do may treat a request
+type requestOptions struct {
+	// idempotent requests can be resent after server errors and network
+	// failures; requests that consume credits must leave this unset
+	idempotent bool
+	// stream disables the per-request timeout so long transfers are
+	// bounded only by the caller's context
+	stream bool
+	// header holds extra request headers, such as Range
+	header http.Header
+}
+
+// do sends a request, waiting on the rate limiter before every attempt and
+// retrying according to the client's retry policy. Once retries are exhausted
+// the last response is returned as-is so callers can report the HTTP error.
+func (c *Client) do(ctx context.Context, method, url string, body []byte, apiKey string, opts requestOptions) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.attempt(ctx, method, url, body, apiKey, opts)
+
+		var wait time.Duration
+		switch {
+		case ctx.Err() != nil:
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, fmt.Errorf("error making request: %w", ctx.Err())
+		case attempt >= c.retry.MaxRetries:
+			if err != nil {
+				return nil, fmt.Errorf("error making request: %w", err)
+			}
+			return resp, nil
+		case err != nil:
+			if !opts.idempotent {
+				return nil, fmt.Errorf("error making request: %w", err)
+			}
+			wait = c.retry.backoff(attempt + 1)
+		case shouldRetry(resp.StatusCode, opts.idempotent):
+			wait = c.retry.backoff(attempt + 1)
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = c.retry.retryAfter(retryAfter)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("error making request: %w", err)
+		}
+	}
+}
+
+// attempt performs a single HTTP round trip, bounded by the per-request
+// timeout unless the request streams
+func (c *Client) attempt(ctx context.Context, method, url string, body []byte, apiKey string, opts requestOptions) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if timeout := c.requestTimeout; timeout > 0 && !opts.stream {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	for name, values := range opts.header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's timeout once its body has been consumed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// endpointURL joins the base URL, an API path and query parameters
+func (c *Client) endpointURL(endpoint string, query url.Values) string {
+	u := c.baseURL + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// send is the request pipeline shared by every endpoint: it resolves the URL
+// against the base URL, encodes payload as JSON, applies auth headers,
+// retries and rate limiting, and converts error statuses into an *APIError.
+// On success the caller owns the response body.
+func (c *Client) send(ctx context.Context, method, endpoint string, query url.Values, payload interface{}, apiKey string, opts requestOptions) (*http.Response, error) {
+	var body []byte
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request: %v", err)
+		}
+		body = jsonData
+	}
+
+	resp, err := c.do(ctx, method, c.endpointURL(endpoint, query), body, apiKey, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+
+	return resp, nil
+}
+
+// call sends a request through send and decodes the JSON response into out.
+// A nil out discards the response body.
+func (c *Client) call(ctx context.Context, method, endpoint string, query url.Values, payload interface{}, apiKey string, out interface{}, opts requestOptions) error {
+	resp, err := c.send(ctx, method, endpoint, query, payload, apiKey, opts)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return nil
+}
+
+// Search runs a search and returns all results in a single response
+func (c *Client) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	return c.SearchWithPagination(ctx, req, nil)
+}
+
+// SearchWithPagination runs a search, requesting the pages described by pagination.
+// A nil pagination behaves like Search.
+func (c *Client) SearchWithPagination(ctx context.Context, req *SearchRequest, pagination *SearchPaginationParams) (*SearchResponse, error) {
+	apiKey, err := c.resolveAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Searches consume credits, so only rate-limit rejections are retried
+	var result SearchResponse
+	if err := c.call(ctx, "POST", "/search", paginationQuery(pagination), req, apiKey, &result, requestOptions{}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// paginationQuery encodes pagination as search query parameters
+func paginationQuery(pagination *SearchPaginationParams) url.Values {
+	q := url.Values{}
+	if pagination == nil {
+		return q
+	}
+	if pagination.Page != nil {
+		q.Add("page", fmt.Sprintf("%d", *pagination.Page))
+	}
+	if len(pagination.Pages) > 0 {
+		pagesStr := make([]string, len(pagination.Pages))
+		for i, page := range pagination.Pages {
+			pagesStr[i] = fmt.Sprintf("%d", page)
+		}
+		q.Add("pages", strings.Join(pagesStr, ","))
+	}
+	if pagination.PageSize != nil {
+		q.Add("pagesize", fmt.Sprintf("%d", *pagination.PageSize))
+	}
+	return q
+}
+
+// Count returns per-type result counts for a query without fetching the results
+func (c *Client) Count(ctx context.Context, req *CountRequest) (*DetailedCountResponse, error) {
+	apiKey, err := c.resolveAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result DetailedCountResponse
+	if err := c.call(ctx, "POST", "/count/detailed", nil, req, apiKey, &result, requestOptions{}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ValidateAPIKey checks apiKey against the API, independently of the key
+// the client was configured with
+func (c *Client) ValidateAPIKey(ctx context.Context, apiKey string) error {
+	validationReq := map[string]string{
+		"apiKey": apiKey,
+	}
+
+	// Success is 200 OK with no response body
+	err := c.call(ctx, "POST", "/validate", nil, validationReq, apiKey, nil, requestOptions{idempotent: true})
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized:
+			return fmt.Errorf("invalid API key: %w", apiErr)
+		case http.StatusBadRequest:
+			return fmt.Errorf("invalid request: %w", apiErr)
+		}
+	}
+
+	return err
+}
+
+// GetMachineInfo returns the machine information parsed from a log
+func (c *Client) GetMachineInfo(ctx context.Context, uuid string) (*MachineInfoResponse, error) {
+	apiKey, err := c.resolveAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{"uuid": {uuid}}
+
+	var result MachineInfoResponse
+	if err := c.call(ctx, "GET", "/machineinfo", q, nil, apiKey, &result, requestOptions{idempotent: true}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetCredits returns the credit balance of the client's API key
+func (c *Client) GetCredits(ctx context.Context) (*CreditsResponse, error) {
+	apiKey, err := c.resolveAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key travels in the body rather than the Authorization header
+	req := &ApiKeyValidation{
+		ApiKey: apiKey,
+	}
+
+	var result CreditsResponse
+	if err := c.call(ctx, "POST", "/credits", nil, req, "", &result, requestOptions{idempotent: true}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,135 @@
+package keyscore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client for baseURL that does not retry unless opts say so
+func newTestClient(baseURL string, opts ...Option) *Client {
+	opts = append([]Option{WithBaseURL(baseURL), WithAPIKey("key"), WithRetryPolicy(RetryPolicy{})}, opts...)
+	return New(opts...)
+}
+
+func TestClient_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := newTestClient(srv.URL, WithRequestTimeout(time.Second)).GetMachineInfo(context.Background(), "uuid")
+	if err == nil {
+		t.Fatal("GetMachineInfo succeeded, expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %v, expected it to stop after the 1s timeout", elapsed)
+	}
+}
+
+func TestClient_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := newTestClient(srv.URL).ValidateAPIKey(ctx, "key")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ValidateAPIKey error = %v, expected context.Canceled", err)
+	}
+}
+
+func TestClient_UsesOptions(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.Header.Get("User-Agent") != "monitor/1.0" {
+			t.Errorf("User-Agent = %q, expected monitor/1.0", r.Header.Get("User-Agent"))
+		}
+		if r.Header.Get("Authorization") != "Bearer injected-key" && r.URL.Path != "/credits" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/search":
+			w.Write([]byte(`{"results":{"email":["a@b.c"]}}`))
+		case "/count/detailed":
+			w.Write([]byte(`{"counts":{},"total_count":5}`))
+		case "/credits":
+			w.Write([]byte(`{"credits":9}`))
+		}
+	}))
+	defer srv.Close()
+
+	// Neither the environment nor a config file in a home directory may leak in
+	t.Setenv("HOME", "")
+	t.Setenv("CLISCORE_BASE_URL", "http://127.0.0.1:1")
+
+	var keyRequests int
+	c := New(
+		WithBaseURL(srv.URL+"/"),
+		WithUserAgent("monitor/1.0"),
+		WithAPIKeyProvider(APIKeyFunc(func(ctx context.Context) (string, error) {
+			keyRequests++
+			return "injected-key", nil
+		})),
+	)
+	ctx := context.Background()
+
+	if _, err := c.Search(ctx, &SearchRequest{Terms: []string{"a@b.c"}}); err != nil {
+		t.Errorf("Search returned error: %v", err)
+	}
+	if resp, err := c.Count(ctx, &CountRequest{Terms: []string{"a@b.c"}}); err != nil || resp.TotalCount != 5 {
+		t.Errorf("Count = %+v, %v", resp, err)
+	}
+	if resp, err := c.GetCredits(ctx); err != nil || resp.Credits != 9 {
+		t.Errorf("GetCredits = %+v, %v", resp, err)
+	}
+
+	expected := []string{"POST /search", "POST /count/detailed", "POST /credits"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("server saw %v, expected %v", paths, expected)
+	}
+	if keyRequests != 3 {
+		t.Errorf("API key provider called %d times, expected 3", keyRequests)
+	}
+}
+
+func TestClient_APIKeyProviderError(t *testing.T) {
+	providerErr := errors.New("vault sealed")
+	c := New(WithBaseURL("http://127.0.0.1:1"), WithAPIKeyProvider(APIKeyFunc(func(ctx context.Context) (string, error) {
+		return "", providerErr
+	})))
+
+	if _, err := c.GetCredits(context.Background()); !errors.Is(err, providerErr) {
+		t.Errorf("GetCredits error = %v, expected %v", err, providerErr)
+	}
+}
+
+func TestClient_EscapesQueryParameters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("file"); got != "Browsers/Chrome & Co/Passwords.txt" {
+			t.Errorf("file query = %q", got)
+		}
+		w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "Passwords.txt")
+	if err := newTestClient(srv.URL).DownloadFile(context.Background(), "uuid", "Browsers/Chrome & Co/Passwords.txt", outputPath); err != nil {
+		t.Fatalf("DownloadFile returned error: %v", err)
+	}
+}
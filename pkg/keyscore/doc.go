@@ -0,0 +1,25 @@
+// Package keyscore is a Go client for the keyscore API.
+//
+// It is the same client the cliscore command line tool is built on. Create a
+// Client with New and configure it with functional options:
+//
+//	client := keyscore.New(
+//		keyscore.WithAPIKey(os.Getenv("KEYSCORE_API_KEY")),
+//		keyscore.WithUserAgent("exposure-monitor/2.1"),
+//	)
+//	resp, err := client.Search(ctx, &keyscore.SearchRequest{
+//		Terms:  []string{"admin@example.com"},
+//		Types:  []string{"email"},
+//		Source: "xkeyscore",
+//	})
+//
+// Failed API calls return an *APIError, which can be matched against
+// ErrUnauthorized, ErrInsufficientCredits, ErrRateLimited and ErrNotFound
+// with errors.Is.
+//
+// The package follows semantic versioning; Version reports the release.
+// Exported identifiers are only removed or changed in a new major version.
+package keyscore
+
+// Version is the release of this package, sent in the default User-Agent
+const Version = "1.0.0"
@@ -0,0 +1,314 @@
+package keyscore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Suffixes of the files kept next to a download
+const (
+	// PartSuffix names the file a download is written to until it completes
+	// and is verified. An interrupted download resumes from it.
+	PartSuffix = ".part"
+	// ChecksumSuffix names the sidecar holding the SHA-256 of a completed
+	// download, in the format of sha256sum
+	ChecksumSuffix = ".sha256"
+)
+
+// Integrity headers the server may send with a download. Both describe the
+// whole file, also in answer to a range request.
+const (
+	checksumHeader = "X-Checksum-Sha256" // Hex SHA-256
+	digestHeader   = "Digest"            // RFC 3230, e.g. sha-256=<base64>
+)
+
+// ErrIntegrity is returned when a download does not match the hash
+// announced by the server
+var ErrIntegrity = errors.New("download failed integrity check")
+
+// DownloadOptions describes what to download and where
+type DownloadOptions struct {
+	UUID string // Log to download
+	File string // Single file from the log archive; empty downloads the archive
+
+	// OutputPath is where the file is written; it defaults to <uuid>.zip, or
+	// to the base name of File
+	OutputPath string
+
+	// Progress, if set, is called as data arrives, from the goroutine
+	// calling Download. It should return quickly.
+	Progress func(DownloadProgress)
+}
+
+// DownloadProgress reports how far a download has come
+type DownloadProgress struct {
+	Written int64 // Bytes on disk, including Resumed
+	Total   int64 // Size of the file, or -1 when the server did not send it
+	Resumed int64 // Bytes kept from an earlier, interrupted transfer
+}
+
+// DownloadResult describes a completed download
+type DownloadResult struct {
+	Path     string // Where the file was written
+	Size     int64  // Size in bytes
+	SHA256   string // Hex SHA-256 of the whole file
+	Resumed  int64  // Bytes reused from an earlier, interrupted download
+	Verified bool   // The server announced a hash, and it matched
+}
+
+// Download streams a log archive (or a single file from it) to disk.
+//
+// Data is written to OutputPath+PartSuffix and renamed into place once it is
+// complete, so the output path never holds a truncated file. A transfer that
+// breaks off is resumed with a range request, within the client's retry
+// policy and by later calls for the same output path. The SHA-256 of the
+// file is computed while streaming, checked against the hash announced by
+// the server, if any, and written to OutputPath+ChecksumSuffix.
+func (c *Client) Download(ctx context.Context, opts DownloadOptions) (*DownloadResult, error) {
+	apiKey, err := c.resolveAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"uuid": {opts.UUID}}
+	if opts.File != "" {
+		query.Set("file", opts.File)
+	}
+
+	finalPath := opts.OutputPath
+	if finalPath == "" {
+		finalPath = fmt.Sprintf("%s.zip", opts.UUID)
+		if opts.File != "" {
+			finalPath = filepath.Base(opts.File)
+		}
+	}
+	partPath := finalPath + PartSuffix
+
+	restarted := false
+	for attempt := 0; ; attempt++ {
+		result, resumable, err := c.fetch(ctx, query, apiKey, partPath, opts.Progress)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrIntegrity) && result != nil && result.Resumed > 0 && !restarted:
+			// The kept bytes may belong to an older version of the file
+			restarted = true
+			continue
+		case resumable && ctx.Err() == nil && attempt < c.retry.MaxRetries:
+			if err := sleep(ctx, c.retry.backoff(attempt+1)); err != nil {
+				return nil, fmt.Errorf("error writing file: %w", err)
+			}
+			continue
+		default:
+			return nil, err
+		}
+
+		if err := os.Rename(partPath, finalPath); err != nil {
+			return nil, fmt.Errorf("error writing file: %v", err)
+		}
+		sidecar := fmt.Sprintf("%s  %s\n", result.SHA256, filepath.Base(finalPath))
+		if err := os.WriteFile(finalPath+ChecksumSuffix, []byte(sidecar), 0644); err != nil {
+			return nil, fmt.Errorf("error writing checksum file: %v", err)
+		}
+		result.Path = finalPath
+		return result, nil
+	}
+}
+
+// DownloadFile downloads a log archive, or the file filePath from it, to
+// outputPath.
+//
+// Deprecated: use Download, which reports progress and returns the path and
+// checksum of the file.
+func (c *Client) DownloadFile(ctx context.Context, uuid, filePath, outputPath string) error {
+	_, err := c.Download(ctx, DownloadOptions{UUID: uuid, File: filePath, OutputPath: outputPath})
+	return err
+}
+
+// fetch requests the part of the download missing from partPath, appends it
+// and hashes the whole file. resumable reports whether a failure left bytes
+// worth resuming from.
+func (c *Client) fetch(ctx context.Context, query url.Values, apiKey, partPath string, progress func(DownloadProgress)) (result *DownloadResult, resumable bool, err error) {
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	opts := requestOptions{idempotent: true, stream: true}
+	if offset > 0 {
+		opts.header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+	}
+	resp, err := c.send(ctx, "GET", "/download", query, nil, apiKey, opts)
+	var apiErr *APIError
+	if offset > 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// The kept bytes do not fit the file on the server: start over
+		if err := os.Remove(partPath); err != nil {
+			return nil, false, fmt.Errorf("error removing partial download: %v", err)
+		}
+		return c.fetch(ctx, query, apiKey, partPath, progress)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	sum := sha256.New()
+	flags := os.O_CREATE | os.O_WRONLY
+	total := int64(-1)
+	if resp.StatusCode == http.StatusPartialContent {
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			os.Remove(partPath)
+			return nil, true, fmt.Errorf("error writing file: unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		if err := hashFile(sum, partPath); err != nil {
+			return nil, false, err
+		}
+		flags |= os.O_APPEND
+		total = size
+	} else {
+		// The server sent the whole file
+		offset = 0
+		flags |= os.O_TRUNC
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating file: %v", err)
+	}
+	var w io.Writer = io.MultiWriter(out, sum)
+	if progress != nil {
+		counter := &progressWriter{progress: progress, state: DownloadProgress{Written: offset, Total: total, Resumed: offset}}
+		progress(counter.state)
+		w = io.MultiWriter(w, counter)
+	}
+	n, err := io.Copy(w, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	result = &DownloadResult{Size: offset + n, Resumed: offset}
+	if err != nil {
+		return result, true, fmt.Errorf("error writing file: %w", err)
+	}
+	if total >= 0 && result.Size != total {
+		return result, true, fmt.Errorf("error writing file: received %d of %d bytes", result.Size, total)
+	}
+
+	result.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	if expected := announcedSHA256(resp.Header); expected != "" {
+		if !strings.EqualFold(expected, result.SHA256) {
+			os.Remove(partPath)
+			return result, false, fmt.Errorf("%w: SHA-256 is %s, server announced %s", ErrIntegrity, result.SHA256, expected)
+		}
+		result.Verified = true
+	}
+	return result, false, nil
+}
+
+// VerifyDownload checks a completed download against the SHA-256 in its
+// ChecksumSuffix sidecar and returns the hash. A mismatch is reported as
+// ErrIntegrity.
+func VerifyDownload(path string) (string, error) {
+	data, err := os.ReadFile(path + ChecksumSuffix)
+	if err != nil {
+		return "", fmt.Errorf("error reading checksum file: %v", err)
+	}
+	expected, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+
+	sum := sha256.New()
+	if err := hashFile(sum, path); err != nil {
+		return "", err
+	}
+	actual := hex.EncodeToString(sum.Sum(nil))
+	if !strings.EqualFold(expected, actual) {
+		return "", fmt.Errorf("%w: SHA-256 of %s is %s, %s%s records %s", ErrIntegrity, path, actual, filepath.Base(path), ChecksumSuffix, expected)
+	}
+	return actual, nil
+}
+
+// progressWriter counts the bytes written through it and reports them
+type progressWriter struct {
+	progress func(DownloadProgress)
+	state    DownloadProgress
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.state.Written += int64(len(p))
+	w.progress(w.state)
+	return len(p), nil
+}
+
+// hashFile feeds the content of path to h
+func hashFile(h hash.Hash, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading file: %v", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("error reading file: %v", err)
+	}
+	return nil
+}
+
+// parseContentRange parses a Content-Range header such as
+// "bytes 100-199/200". size is -1 when the total is unknown.
+func parseContentRange(value string) (start, size int64, ok bool) {
+	value, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	span, total, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(span, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if total == "*" {
+		return start, -1, true
+	}
+	size, err = strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
+
+// announcedSHA256 returns the hex SHA-256 the server sent for a download,
+// or "" when it sent none
+func announcedSHA256(header http.Header) string {
+	if sum := strings.TrimSpace(header.Get(checksumHeader)); sum != "" {
+		return sum
+	}
+	for _, digest := range strings.Split(header.Get(digestHeader), ",") {
+		algorithm, value, found := strings.Cut(strings.TrimSpace(digest), "=")
+		if !found || !strings.EqualFold(algorithm, "sha-256") {
+			continue
+		}
+		if raw, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return hex.EncodeToString(raw)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package keyscore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// archiveServer serves content at /download, honouring Range requests. The
+// first drops responses are cut off after half the content is sent.
+type archiveServer struct {
+	content []byte
+	drops   int
+	header  http.Header // Extra response headers, such as checksums
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (s *archiveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	drop := s.drops > 0
+	s.drops--
+	s.mu.Unlock()
+
+	for name, values := range s.header {
+		w.Header()[name] = values
+	}
+
+	body := s.content
+	if spec := strings.TrimPrefix(r.Header.Get("Range"), "bytes="); spec != "" {
+		start, _ := strconv.Atoi(strings.TrimSuffix(spec, "-"))
+		if start >= len(s.content) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		body = s.content[start:]
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.content)-1, len(s.content)))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+
+	if drop {
+		w.Write(body[:len(body)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	w.Write(body)
+}
+
+func testArchive() ([]byte, string) {
+	content := bytes.Repeat([]byte("PK log archive bytes "), 4096)
+	sum := sha256.Sum256(content)
+	return content, hex.EncodeToString(sum[:])
+}
+
+func TestDownload_ResumesDroppedConnection(t *testing.T) {
+	content, sum := testArchive()
+	archive := &archiveServer{content: content, drops: 1, header: http.Header{"X-Checksum-Sha256": {sum}}}
+	srv := httptest.NewServer(archive)
+	defer srv.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "log.zip")
+	client := newTestClient(srv.URL, WithRetryPolicy(RetryPolicy{MaxRetries: 2}))
+	result, err := client.Download(context.Background(), DownloadOptions{UUID: "uuid", OutputPath: outputPath})
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	if result.Resumed != int64(len(content)/2) || !result.Verified || result.SHA256 != sum || result.Size != int64(len(content)) {
+		t.Errorf("result = %+v, expected a verified download resumed after %d bytes", result, len(content)/2)
+	}
+	if want := fmt.Sprintf("bytes=%d-", len(content)/2); len(archive.ranges) != 2 || archive.ranges[1] != want {
+		t.Errorf("ranges requested = %q, expected a retry with %q", archive.ranges, want)
+	}
+	assertDownloaded(t, outputPath, content, sum)
+}
+
+func TestDownload_ResumesAcrossCalls(t *testing.T) {
+	content, sum := testArchive()
+	archive := &archiveServer{content: content, drops: 1}
+	srv := httptest.NewServer(archive)
+	defer srv.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "log.zip")
+	client := newTestClient(srv.URL)
+	if _, err := client.Download(context.Background(), DownloadOptions{UUID: "uuid", OutputPath: outputPath}); err == nil {
+		t.Fatal("Download succeeded over a dropped connection without retries")
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("output path exists after a failed download: %v", err)
+	}
+	if info, err := os.Stat(outputPath + PartSuffix); err != nil || info.Size() != int64(len(content)/2) {
+		t.Fatalf("partial download = %v, %v, expected %d bytes", info, err, len(content)/2)
+	}
+
+	var reports []DownloadProgress
+	result, err := client.Download(context.Background(), DownloadOptions{
+		UUID:       "uuid",
+		OutputPath: outputPath,
+		Progress:   func(p DownloadProgress) { reports = append(reports, p) },
+	})
+	if err != nil {
+		t.Fatalf("resumed Download: %v", err)
+	}
+	if result.Resumed != int64(len(content)/2) || result.Verified {
+		t.Errorf("result = %+v, expected an unverified resumed download", result)
+	}
+
+	half, total := int64(len(content)/2), int64(len(content))
+	if len(reports) < 2 || reports[0] != (DownloadProgress{Written: half, Total: total, Resumed: half}) {
+		t.Fatalf("progress = %+v, expected to start at the resumed offset", reports)
+	}
+	for i, p := range reports[1:] {
+		if p.Written < reports[i].Written || p.Total != total {
+			t.Errorf("progress report %d = %+v after %+v", i+1, p, reports[i])
+		}
+	}
+	if last := reports[len(reports)-1]; last.Written != total {
+		t.Errorf("last progress report = %+v, expected all %d bytes", last, total)
+	}
+	assertDownloaded(t, outputPath, content, sum)
+}
+
+func TestDownload_RestartsStalePartialFile(t *testing.T) {
+	content, sum := testArchive()
+	raw, _ := hex.DecodeString(sum)
+	digest := http.Header{"Digest": {"md5=abc, sha-256=" + base64.StdEncoding.EncodeToString(raw)}}
+
+	tests := []struct {
+		name string
+		part []byte
+	}{
+		{"other version", []byte("stale bytes of another archive")},
+		{"longer than the file", bytes.Repeat([]byte("x"), len(content)+1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(&archiveServer{content: content, header: digest})
+			defer srv.Close()
+
+			outputPath := filepath.Join(t.TempDir(), "log.zip")
+			if err := os.WriteFile(outputPath+PartSuffix, test.part, 0644); err != nil {
+				t.Fatal(err)
+			}
+			result, err := newTestClient(srv.URL).Download(context.Background(), DownloadOptions{UUID: "uuid", OutputPath: outputPath})
+			if err != nil {
+				t.Fatalf("Download: %v", err)
+			}
+			if result.Resumed != 0 || !result.Verified {
+				t.Errorf("result = %+v, expected a verified download from scratch", result)
+			}
+			assertDownloaded(t, outputPath, content, sum)
+		})
+	}
+}
+
+func TestDownload_RejectsHashMismatch(t *testing.T) {
+	content, _ := testArchive()
+	srv := httptest.NewServer(&archiveServer{content: content, header: http.Header{"X-Checksum-Sha256": {strings.Repeat("0", 64)}}})
+	defer srv.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "log.zip")
+	_, err := newTestClient(srv.URL).Download(context.Background(), DownloadOptions{UUID: "uuid", OutputPath: outputPath})
+	if !errors.Is(err, ErrIntegrity) {
+		t.Fatalf("Download error = %v, expected ErrIntegrity", err)
+	}
+	for _, path := range []string{outputPath, outputPath + PartSuffix, outputPath + ChecksumSuffix} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s exists after a failed verification", filepath.Base(path))
+		}
+	}
+}
+
+func TestVerifyDownload(t *testing.T) {
+	content, sum := testArchive()
+	srv := httptest.NewServer(&archiveServer{content: content})
+	defer srv.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "log.zip")
+	if _, err := newTestClient(srv.URL).Download(context.Background(), DownloadOptions{UUID: "uuid", OutputPath: outputPath}); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if got, err := VerifyDownload(outputPath); err != nil || got != sum {
+		t.Errorf("VerifyDownload = %q, %v, expected %q", got, err, sum)
+	}
+
+	if err := os.WriteFile(outputPath, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyDownload(outputPath); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("VerifyDownload of a changed file error = %v, expected ErrIntegrity", err)
+	}
+	if _, err := VerifyDownload(filepath.Join(t.TempDir(), "missing.zip")); err == nil || errors.Is(err, ErrIntegrity) {
+		t.Errorf("VerifyDownload without a checksum file error = %v", err)
+	}
+}
+
+// assertDownloaded checks that path holds content and its checksum sidecar
+func assertDownloaded(t *testing.T, path string, content []byte, sum string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("downloaded file has %d bytes (%v), expected the %d bytes served", len(data), err, len(content))
+	}
+	if _, err := os.Stat(path + PartSuffix); !os.IsNotExist(err) {
+		t.Errorf("partial download was left behind: %v", err)
+	}
+	sidecar, err := os.ReadFile(path + ChecksumSuffix)
+	if want := sum + "  " + filepath.Base(path) + "\n"; err != nil || string(sidecar) != want {
+		t.Errorf("checksum file = %q (%v), expected %q", sidecar, err, want)
+	}
+}
@@ -0,0 +1,118 @@
+package keyscore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors matched by APIError through errors.Is
+var (
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrInsufficientCredits = errors.New("insufficient credits")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrNotFound            = errors.New("not found")
+)
+
+// maxErrorBody caps how much of an error response is read into memory
+const maxErrorBody = 64 << 10
+
+// APIError describes a non-successful response from the API
+type APIError struct {
+	StatusCode int           // HTTP status code
+	Message    string        // Error message parsed from the response body
+	Body       string        // Raw response body
+	RequestID  string        // Server-assigned request ID, if any
+	Endpoint   string        // Method and path of the failed request
+	RetryAfter time.Duration // Wait requested by the server on 429/503 responses
+}
+
+func (e *APIError) Error() string {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "HTTP %d", e.StatusCode)
+	if e.Message != "" {
+		fmt.Fprintf(&msg, ": %s", e.Message)
+	}
+	if e.Endpoint != "" {
+		fmt.Fprintf(&msg, " (%s)", e.Endpoint)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&msg, " [request %s]", e.RequestID)
+	}
+	return msg.String()
+}
+
+// Is lets errors.Is match an APIError against the package sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrInsufficientCredits:
+		return e.StatusCode == http.StatusPaymentRequired ||
+			(e.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(e.Message), "credit"))
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// newAPIError builds an APIError from a failed response, consuming its body
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Message:    parseErrorMessage(body),
+		RequestID:  requestID(resp.Header),
+	}
+	if resp.Request != nil {
+		apiErr.Endpoint = resp.Request.Method + " " + resp.Request.URL.Path
+	}
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		apiErr.RetryAfter = retryAfter
+	}
+	return apiErr
+}
+
+// parseErrorMessage extracts a human readable message from an error body,
+// accepting {"error": "..."}, {"message": "..."}, {"error": {"message": "..."}}
+// or plain text
+func parseErrorMessage(body []byte) string {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		var text string
+		if json.Unmarshal(parsed.Error, &text) == nil && text != "" {
+			return text
+		}
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(parsed.Error, &nested) == nil && nested.Message != "" {
+			return nested.Message
+		}
+		if parsed.Message != "" {
+			return parsed.Message
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// requestID returns the request correlation ID sent by the server, if any
+func requestID(header http.Header) string {
+	for _, name := range []string{"X-Request-Id", "X-Correlation-Id", "Cf-Ray"} {
+		if id := header.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package keyscore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError_Sentinels(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		expected error
+	}{
+		{http.StatusUnauthorized, `{"error":"invalid token"}`, ErrUnauthorized},
+		{http.StatusPaymentRequired, `{"error":"out of credits"}`, ErrInsufficientCredits},
+		{http.StatusForbidden, `{"message":"Not enough credits"}`, ErrInsufficientCredits},
+		{http.StatusTooManyRequests, ``, ErrRateLimited},
+		{http.StatusNotFound, `not found`, ErrNotFound},
+	}
+
+	sentinels := []error{ErrUnauthorized, ErrInsufficientCredits, ErrRateLimited, ErrNotFound}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}))
+
+		_, err := newTestClient(srv.URL).GetMachineInfo(context.Background(), "uuid")
+		srv.Close()
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("status %d: error %v is not an *APIError", test.status, err)
+		}
+		if apiErr.StatusCode != test.status || apiErr.Endpoint != "GET /machineinfo" {
+			t.Errorf("status %d: got %+v", test.status, apiErr)
+		}
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == test.expected) {
+				t.Errorf("status %d: errors.Is(err, %v) = %v", test.status, sentinel, got)
+			}
+		}
+	}
+}
+
+func TestAPIError_Message(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc-123")
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"message":"slow down"}}`))
+	}))
+	defer srv.Close()
+
+	err := newTestClient(srv.URL).DownloadFile(context.Background(), "uuid", "", t.TempDir()+"/out.zip")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error %v is not an *APIError", err)
+	}
+	if apiErr.Message != "slow down" || apiErr.RequestID != "abc-123" || apiErr.RetryAfter.Seconds() != 7 {
+		t.Errorf("unexpected APIError fields: %+v", apiErr)
+	}
+	if expected := "HTTP 429: slow down (GET /download) [request abc-123]"; err.Error() != expected {
+		t.Errorf("Error() = %q, expected %q", err.Error(), expected)
+	}
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"error":"bad query"}`, "bad query"},
+		{`{"message":"bad query"}`, "bad query"},
+		{`{"error":{"message":"bad query"}}`, "bad query"},
+		{"  plain text\n", "plain text"},
+		{``, ""},
+	}
+
+	for _, test := range tests {
+		if result := parseErrorMessage([]byte(test.input)); result != test.expected {
+			t.Errorf("parseErrorMessage(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
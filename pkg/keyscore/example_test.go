@@ -0,0 +1,93 @@
+package keyscore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"cliscore/pkg/keyscore"
+)
+
+// fakeAPI stands in for the keyscore API so the examples are runnable
+func fakeAPI() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results":{"email":[{"login":"admin@example.com","url":"https://example.com/login"}]}}`)
+	})
+	mux.HandleFunc("/count/detailed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"counts":{"email":12},"total_count":12,"took":4}`)
+	})
+	mux.HandleFunc("/credits", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+		fmt.Fprint(w, `{"error":"no credits left"}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func ExampleNew() {
+	client := keyscore.New(
+		keyscore.WithAPIKey(os.Getenv("KEYSCORE_API_KEY")),
+		keyscore.WithUserAgent("exposure-monitor/2.1"),
+		keyscore.WithRequestTimeout(30*time.Second),
+		keyscore.WithRateLimit(5, 10),
+	)
+	_ = client
+}
+
+func ExampleClient_Search() {
+	srv := fakeAPI()
+	defer srv.Close()
+
+	client := keyscore.New(keyscore.WithBaseURL(srv.URL), keyscore.WithAPIKey("my-key"))
+
+	resp, err := client.Search(context.Background(), &keyscore.SearchRequest{
+		Terms:  []string{"admin@example.com"},
+		Types:  []string{"email"},
+		Source: "xkeyscore",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(len(resp.Results["email"].([]interface{})), "result(s)")
+	// Output: 1 result(s)
+}
+
+func ExampleClient_Count() {
+	srv := fakeAPI()
+	defer srv.Close()
+
+	client := keyscore.New(keyscore.WithBaseURL(srv.URL), keyscore.WithAPIKey("my-key"))
+
+	resp, err := client.Count(context.Background(), &keyscore.CountRequest{
+		Terms:  []string{"example.com"},
+		Types:  []string{"email_domain"},
+		Source: "xkeyscore",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(resp.TotalCount)
+	// Output: 12
+}
+
+func ExampleWithAPIKeyProvider() {
+	srv := fakeAPI()
+	defer srv.Close()
+
+	// Fetch the key lazily, e.g. from a secret store that rotates it
+	provider := keyscore.APIKeyFunc(func(ctx context.Context) (string, error) {
+		return "rotated-key", nil
+	})
+	client := keyscore.New(keyscore.WithBaseURL(srv.URL), keyscore.WithAPIKeyProvider(provider))
+
+	_, err := client.GetCredits(context.Background())
+	if errors.Is(err, keyscore.ErrInsufficientCredits) {
+		fmt.Println("out of credits:", err)
+	}
+	// Output: out of credits: HTTP 402: no credits left (POST /credits)
+}
@@ -1,17 +1,17 @@
-package models
+package keyscore
 
 type SearchRequest struct {
-	Terms     []string `json:"terms"`
-	Types     []string `json:"types"`
-	Wildcard  bool     `json:"wildcard"`
-	Source    string   `json:"source"`
-	Operator  *string  `json:"operator,omitempty"`
+	Terms    []string `json:"terms"`
+	Types    []string `json:"types"`
+	Wildcard bool     `json:"wildcard"`
+	Source   string   `json:"source"`
+	Operator *string  `json:"operator,omitempty"`
 }
 
 type SearchPaginationParams struct {
-	Page     *int    `json:"-"` // Will be sent as query parameter
-	Pages    []int   `json:"-"` // Will be sent as query parameter
-	PageSize *int    `json:"-"` // Will be sent as query parameter
+	Page     *int  `json:"-"` // Will be sent as query parameter
+	Pages    []int `json:"-"` // Will be sent as query parameter
+	PageSize *int  `json:"-"` // Will be sent as query parameter
 }
 
 type CountRequest struct {
@@ -23,10 +23,10 @@ type CountRequest struct {
 }
 
 type SearchResponse struct {
-	Results map[string]interface{} `json:"results"`
+	Results map[string]interface{}         `json:"results"`
 	Pages   map[int]map[string]interface{} `json:"pages,omitempty"`
-	Size    int64  `json:"size,omitempty"`
-	Took    int64  `json:"took,omitempty"`
+	Size    int64                          `json:"size,omitempty"`
+	Took    int64                          `json:"took,omitempty"`
 }
 
 type CountResponse struct {
@@ -50,11 +50,6 @@ type ValidationResponse struct {
 	Role    string `json:"role,omitempty"`
 }
 
-type Config struct {
-	BaseURL string
-	APIKey  string
-}
-
 type MachineInfoRequest struct {
 	UUID string `json:"uuid"`
 }
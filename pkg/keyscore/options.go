@@ -0,0 +1,96 @@
+package keyscore
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public keyscore API endpoint
+const DefaultBaseURL = "https://api.keysco.re"
+
+// DefaultRequestTimeout bounds a single request when WithRequestTimeout is not used
+const DefaultRequestTimeout = 60 * time.Second
+
+// Option configures a Client
+type Option func(*Client)
+
+// APIKeyProvider supplies the API key for each request, allowing keys to be
+// rotated or fetched from a secret store without recreating the client
+type APIKeyProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// APIKeyFunc adapts a function to the APIKeyProvider interface
+type APIKeyFunc func(ctx context.Context) (string, error)
+
+// APIKey calls f(ctx)
+func (f APIKeyFunc) APIKey(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticAPIKey is an APIKeyProvider that always returns the same key
+type StaticAPIKey string
+
+// APIKey returns the key itself
+func (k StaticAPIKey) APIKey(context.Context) (string, error) {
+	return string(k), nil
+}
+
+// WithBaseURL points the client at a different API endpoint
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient makes the client send requests through httpClient. The
+// client's own timeouts still apply through request contexts.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey authenticates every request with a fixed API key
+func WithAPIKey(apiKey string) Option {
+	return WithAPIKeyProvider(StaticAPIKey(apiKey))
+}
+
+// WithAPIKeyProvider authenticates requests with keys obtained from provider
+func WithAPIKeyProvider(provider APIKeyProvider) Option {
+	return func(c *Client) {
+		c.apiKeys = provider
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRequestTimeout bounds each HTTP request (0 disables the timeout).
+// Downloads are only bounded by the caller's context once headers arrive.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithRetryPolicy replaces the default retry policy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithRateLimit limits the client to rate requests per second with the
+// given burst (a rate of 0 removes the limit)
+func WithRateLimit(rate float64, burst int) Option {
+	return func(c *Client) {
+		c.limiter = newRateLimiter(rate, burst)
+	}
+}
@@ -0,0 +1,178 @@
+package keyscore
+
+import (
+	"context"
+	"sync"
+)
+
+// Page size limits enforced by the API
+const (
+	DefaultPageSize = 1000
+	MaxPageSize     = 10000
+)
+
+// SearchAllOptions controls how SearchAll walks the pages of a search
+type SearchAllOptions struct {
+	PageSize    int   // Results per page (DefaultPageSize when 0, capped at MaxPageSize)
+	Concurrency int   // Pages fetched in parallel after the first (1 when 0)
+	MaxResults  int64 // Stop once the fetched pages cover this many results (0 = no limit)
+
+	// MaxCredits stops fetching before the search would spend more than this
+	// many credits (0 = no limit). The cost of a page is measured from the
+	// credit balance before and after the first page is fetched.
+	MaxCredits int64
+
+	// Progress, when set, is called after every fetched page. Calls are
+	// serialized but may come from different goroutines.
+	Progress func(SearchProgress)
+}
+
+// SearchProgress reports how far SearchAll has got
+type SearchProgress struct {
+	PagesFetched int   // Pages fetched so far
+	TotalPages   int   // Pages that will be fetched in total
+	Total        int64 // Total results matching the search
+}
+
+// SearchAll fetches every page of a search, or as many as the ceilings in
+// opts allow, and merges them into one response whose Pages are keyed by
+// page number. Size always reports the total number of matching results, so
+// callers can tell a truncated walk from a complete one. If a page fails the
+// pages fetched so far are returned along with the error.
+func (c *Client) SearchAll(ctx context.Context, req *SearchRequest, opts SearchAllOptions) (*SearchResponse, error) {
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var creditsBefore int64
+	if opts.MaxCredits > 0 {
+		credits, err := c.GetCredits(ctx)
+		if err != nil {
+			return nil, err
+		}
+		creditsBefore = credits.Credits
+	}
+
+	first, err := c.searchPage(ctx, req, 1, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &SearchResponse{
+		Pages: map[int]map[string]interface{}{1: pageResults(first, 1)},
+		Size:  first.Size,
+		Took:  first.Took,
+	}
+
+	totalPages := int((first.Size + int64(pageSize) - 1) / int64(pageSize))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+	if opts.MaxResults > 0 {
+		if maxPages := int((opts.MaxResults + int64(pageSize) - 1) / int64(pageSize)); maxPages < totalPages {
+			totalPages = maxPages
+		}
+	}
+	if opts.MaxCredits > 0 && totalPages > 1 {
+		credits, err := c.GetCredits(ctx)
+		if err != nil {
+			return merged, err
+		}
+		if cost := creditsBefore - credits.Credits; cost > 0 {
+			if maxPages := int(opts.MaxCredits / cost); maxPages < totalPages {
+				totalPages = maxPages
+			}
+		}
+		if totalPages < 1 {
+			totalPages = 1
+		}
+	}
+
+	var mu sync.Mutex
+	report := func() {
+		if opts.Progress != nil {
+			opts.Progress(SearchProgress{PagesFetched: len(merged.Pages), TotalPages: totalPages, Total: merged.Size})
+		}
+	}
+	report()
+
+	if totalPages == 1 {
+		return merged, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make(chan int)
+	var wg sync.WaitGroup
+	var firstErr error
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				resp, err := c.searchPage(ctx, req, page, pageSize)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else {
+					merged.Pages[page] = pageResults(resp, page)
+					if resp.Took > merged.Took {
+						merged.Took = resp.Took
+					}
+					report()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for page := 2; page <= totalPages; page++ {
+		select {
+		case pages <- page:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pages)
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	return merged, firstErr
+}
+
+// searchPage fetches a single page of a search
+func (c *Client) searchPage(ctx context.Context, req *SearchRequest, page, pageSize int) (*SearchResponse, error) {
+	return c.SearchWithPagination(ctx, req, &SearchPaginationParams{Page: &page, PageSize: &pageSize})
+}
+
+// pageResults extracts the results of page from a paginated response,
+// falling back to Results for servers that answer single-page requests
+// without the pages envelope
+func pageResults(resp *SearchResponse, page int) map[string]interface{} {
+	if results, ok := resp.Pages[page]; ok {
+		return results
+	}
+	if len(resp.Pages) == 1 {
+		for _, results := range resp.Pages {
+			return results
+		}
+	}
+	return resp.Results
+}
@@ -0,0 +1,114 @@
+package keyscore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// newPagedServer serves a search with total results split into pages,
+// charging cost credits per search and failing failPage when it is set
+func newPagedServer(t *testing.T, total int64, cost int64, failPage int) (*httptest.Server, *int64) {
+	t.Helper()
+
+	var mu sync.Mutex
+	credits := int64(1000)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/credits":
+			json.NewEncoder(w).Encode(map[string]int64{"credits": credits})
+		case "/search":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == failPage {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			credits -= cost
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"pages": map[string]interface{}{strconv.Itoa(page): map[string]interface{}{"email": []int{page}}},
+				"size":  total,
+			})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &credits
+}
+
+func TestClient_SearchAll(t *testing.T) {
+	srv, _ := newPagedServer(t, 25, 1, 0)
+
+	var progress []SearchProgress
+	resp, err := newTestClient(srv.URL).SearchAll(context.Background(), &SearchRequest{Terms: []string{"x"}}, SearchAllOptions{
+		PageSize:    10,
+		Concurrency: 3,
+		Progress:    func(p SearchProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("SearchAll returned error: %v", err)
+	}
+
+	if len(resp.Pages) != 3 || resp.Size != 25 {
+		t.Fatalf("SearchAll returned %d pages of %d results, expected 3 of 25", len(resp.Pages), resp.Size)
+	}
+	for page := 1; page <= 3; page++ {
+		got := resp.Pages[page]["email"].([]interface{})[0].(float64)
+		if int(got) != page {
+			t.Errorf("page %d holds results of page %v", page, got)
+		}
+	}
+
+	last := progress[len(progress)-1]
+	if len(progress) != 3 || last.PagesFetched != 3 || last.TotalPages != 3 {
+		t.Errorf("progress = %+v, expected 3 reports ending at 3/3", progress)
+	}
+}
+
+func TestClient_SearchAll_Ceilings(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     SearchAllOptions
+		cost     int64
+		expected int
+	}{
+		{"max results", SearchAllOptions{PageSize: 10, MaxResults: 15}, 1, 2},
+		{"max results on page boundary", SearchAllOptions{PageSize: 10, MaxResults: 30}, 1, 3},
+		{"max credits", SearchAllOptions{PageSize: 10, MaxCredits: 4}, 2, 2},
+		{"max credits below one page", SearchAllOptions{PageSize: 10, MaxCredits: 1}, 2, 1},
+		{"free pages", SearchAllOptions{PageSize: 10, MaxCredits: 1}, 0, 5},
+	}
+
+	for _, test := range tests {
+		srv, credits := newPagedServer(t, 50, test.cost, 0)
+
+		resp, err := newTestClient(srv.URL).SearchAll(context.Background(), &SearchRequest{Terms: []string{"x"}}, test.opts)
+		if err != nil {
+			t.Fatalf("%s: SearchAll returned error: %v", test.name, err)
+		}
+		if len(resp.Pages) != test.expected {
+			t.Errorf("%s: fetched %d pages, expected %d", test.name, len(resp.Pages), test.expected)
+		}
+		if spent := 1000 - *credits; test.opts.MaxCredits > 0 && test.cost > 0 && spent > test.opts.MaxCredits && test.expected > 1 {
+			t.Errorf("%s: spent %d credits, ceiling was %d", test.name, spent, test.opts.MaxCredits)
+		}
+	}
+}
+
+func TestClient_SearchAll_PageError(t *testing.T) {
+	srv, _ := newPagedServer(t, 50, 1, 3)
+
+	resp, err := newTestClient(srv.URL).SearchAll(context.Background(), &SearchRequest{Terms: []string{"x"}}, SearchAllOptions{PageSize: 10})
+	if err == nil {
+		t.Fatal("SearchAll succeeded, expected the page 3 error")
+	}
+	if resp == nil || len(resp.Pages) != 2 {
+		t.Errorf("SearchAll kept %v, expected pages 1 and 2", resp)
+	}
+}
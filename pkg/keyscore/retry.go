@@ -0,0 +1,83 @@
+package keyscore
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt (0 disables retries)
+	BaseDelay  time.Duration // Delay before the first retry, doubled on each attempt
+	MaxDelay   time.Duration // Upper bound for a single backoff delay
+}
+
+// DefaultRetryPolicy is used when the config does not override the retry count
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
+// backoff returns the delay before retry number attempt (starting at 1),
+// using exponential growth with full jitter
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+// retryAfter returns the wait a server asked for with Retry-After, capped at
+// MaxDelay so a hostile or broken server cannot stall the client
+func (p RetryPolicy) retryAfter(wait time.Duration) time.Duration {
+	if p.MaxDelay > 0 && wait > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return wait
+}
+
+// shouldRetry reports whether a response with the given status may be retried.
+// A 429 means the server rejected the request before doing any work, so it is
+// safe to resend even when the request consumes credits. Server errors are
+// only retried for idempotent requests, since the server may already have
+// charged for a request that failed late.
+func shouldRetry(status int, idempotent bool) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,181 @@
+package keyscore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryingClient returns a client with fast backoff so tests do not sleep
+func newRetryingClient(baseURL string, maxRetries int) *Client {
+	return newTestClient(baseURL, WithRetryPolicy(RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   5 * time.Millisecond,
+	}))
+}
+
+func TestClient_RetriesIdempotentServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data":{"hostname":"box"}}`))
+	}))
+	defer srv.Close()
+
+	resp, err := newRetryingClient(srv.URL, 3).GetMachineInfo(context.Background(), "uuid")
+	if err != nil {
+		t.Fatalf("GetMachineInfo returned error: %v", err)
+	}
+	if resp.Data == nil || resp.Data.Hostname != "box" {
+		t.Errorf("GetMachineInfo = %+v, expected hostname box", resp)
+	}
+	if calls != 3 {
+		t.Errorf("server saw %d calls, expected 3", calls)
+	}
+}
+
+func TestClient_DoesNotRetryCreditConsumingServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := newRetryingClient(srv.URL, 3)
+	pageSize := 10
+	_, err := c.SearchWithPagination(context.Background(), &SearchRequest{Terms: []string{"x"}}, &SearchPaginationParams{PageSize: &pageSize})
+	if err == nil {
+		t.Fatal("SearchWithPagination succeeded, expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("server saw %d calls, expected 1", calls)
+	}
+}
+
+func TestClient_RetriesRateLimitedRequests(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"results":{}}`))
+	}))
+	defer srv.Close()
+
+	pageSize := 10
+	_, err := newRetryingClient(srv.URL, 3).SearchWithPagination(context.Background(), &SearchRequest{Terms: []string{"x"}}, &SearchPaginationParams{PageSize: &pageSize})
+	if err != nil {
+		t.Fatalf("SearchWithPagination returned error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("server saw %d calls, expected 2", calls)
+	}
+}
+
+func TestClient_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := newRetryingClient(srv.URL, 2).GetMachineInfo(context.Background(), "uuid")
+	if err == nil {
+		t.Fatal("GetMachineInfo succeeded, expected an error")
+	}
+	if calls != 3 {
+		t.Errorf("server saw %d calls, expected 3", calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		result, ok := parseRetryAfter(test.input, now)
+		if result != test.expected || ok != test.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, expected %v, %v", test.input, result, ok, test.expected, test.ok)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		limit := p.BaseDelay << (attempt - 1)
+		if limit > p.MaxDelay {
+			limit = p.MaxDelay
+		}
+		if delay := p.backoff(attempt); delay <= 0 || delay > limit {
+			t.Errorf("backoff(%d) = %v, expected a delay in (0, %v]", attempt, delay, limit)
+		}
+	}
+}
+
+func TestClient_CapsRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"credits":5}`))
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	if _, err := newRetryingClient(srv.URL, 1).GetCredits(context.Background()); err != nil {
+		t.Fatalf("GetCredits returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retry took %v, expected Retry-After to be capped at MaxDelay", elapsed)
+	}
+	if calls != 2 {
+		t.Errorf("server saw %d calls, expected 2", calls)
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	l := newRateLimiter(20, 1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait returned error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("3 requests at 20/s with burst 1 took %v, expected at least 100ms", elapsed)
+	}
+
+	if newRateLimiter(0, 5) != nil {
+		t.Error("newRateLimiter(0, 5) should be unlimited (nil)")
+	}
+}
@@ -0,0 +1,313 @@
+package keyscore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Record is a single search result yielded by a ResultStream
+type Record struct {
+	Page  int         // Page the record came from, 0 for unpaginated searches
+	Group string      // Key of the results object the record was listed under
+	Data  interface{} // The decoded record
+}
+
+// ResultStream decodes a search response incrementally, yielding one record
+// at a time instead of buffering the whole response. Use it like a
+// bufio.Scanner:
+//
+//	for stream.Next() {
+//		rec := stream.Record()
+//	}
+//	if err := stream.Err(); err != nil { ... }
+//
+// Groups whose value is a JSON array yield one record per element; any other
+// value is yielded as a single record. A stream must be closed.
+type ResultStream struct {
+	body    io.ReadCloser
+	records chan Record
+	done    chan struct{}
+	once    sync.Once
+	current Record
+
+	// Set by the decoding goroutine before records is closed
+	err  error
+	size int64
+	took int64
+}
+
+// SearchStream runs a search and returns a stream over its results.
+// pagination may be nil.
+func (c *Client) SearchStream(ctx context.Context, req *SearchRequest, pagination *SearchPaginationParams) (*ResultStream, error) {
+	apiKey, err := c.resolveAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Streams are read for as long as the caller needs, so only ctx bounds them
+	resp, err := c.send(ctx, "POST", "/search", paginationQuery(pagination), req, apiKey, requestOptions{stream: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return newResultStream(resp.Body), nil
+}
+
+func newResultStream(body io.ReadCloser) *ResultStream {
+	s := &ResultStream{
+		body:    body,
+		records: make(chan Record),
+		done:    make(chan struct{}),
+	}
+	go s.decode()
+	return s
+}
+
+// Next advances to the next record, returning false at the end of the
+// response or on error
+func (s *ResultStream) Next() bool {
+	rec, ok := <-s.records
+	if !ok {
+		return false
+	}
+	s.current = rec
+	return true
+}
+
+// Record returns the record read by the last call to Next
+func (s *ResultStream) Record() Record {
+	return s.current
+}
+
+// Err returns the first decoding error, if any, once Next has returned false
+func (s *ResultStream) Err() error {
+	return s.err
+}
+
+// Size returns the total number of matching results reported by the server.
+// It is only reliable once Next has returned false.
+func (s *ResultStream) Size() int64 {
+	return s.size
+}
+
+// Took returns the server-side search time in milliseconds once Next has returned false
+func (s *ResultStream) Took() int64 {
+	return s.took
+}
+
+// Close stops decoding and releases the response body
+func (s *ResultStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = s.body.Close()
+		for range s.records {
+		}
+	})
+	return err
+}
+
+// errStreamClosed stops the decoder when the consumer closes the stream early
+var errStreamClosed = errors.New("stream closed")
+
+func (s *ResultStream) decode() {
+	defer close(s.records)
+
+	err := s.walk(json.NewDecoder(s.body))
+	if err != nil && !errors.Is(err, errStreamClosed) {
+		select {
+		case <-s.done:
+		default:
+			s.err = fmt.Errorf("error parsing response: %w", err)
+		}
+	}
+}
+
+// walk reads the top-level response object, streaming "results" and "pages"
+func (s *ResultStream) walk(dec *json.Decoder) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "results":
+			err = s.walkGroups(dec, 0)
+		case "pages":
+			err = s.walkPages(dec)
+		case "size":
+			err = dec.Decode(&s.size)
+		case "took":
+			err = dec.Decode(&s.took)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// walkPages reads {"<page>": {<groups>}, ...}
+func (s *ResultStream) walkPages(dec *json.Decoder) error {
+	if isNull, err := skipNull(dec, '{'); err != nil || isNull {
+		return err
+	}
+
+	for dec.More() {
+		key, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+		page, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid page number %q", key)
+		}
+		if err := s.walkGroups(dec, page); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// walkGroups reads {"<group>": [<records>] | <record>, ...}
+func (s *ResultStream) walkGroups(dec *json.Decoder, page int) error {
+	if isNull, err := skipNull(dec, '{'); err != nil || isNull {
+		return err
+	}
+
+	for dec.More() {
+		group, err := readKey(dec)
+		if err != nil {
+			return err
+		}
+
+		isArray, data, err := groupValue(dec)
+		if err != nil {
+			return err
+		}
+		if !isArray {
+			if data == nil {
+				continue
+			}
+			if err := s.emit(Record{Page: page, Group: group, Data: data}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for dec.More() {
+			var data interface{}
+			if err := dec.Decode(&data); err != nil {
+				return err
+			}
+			if err := s.emit(Record{Page: page, Group: group, Data: data}); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// emit hands a record to the consumer, giving up if the stream was closed
+func (s *ResultStream) emit(rec Record) error {
+	select {
+	case s.records <- rec:
+		return nil
+	case <-s.done:
+		return errStreamClosed
+	}
+}
+
+// groupValue reads the value of a results group. For arrays only the
+// opening bracket is consumed so the elements can be streamed; any other
+// value is decoded whole and returned.
+func groupValue(dec *json.Decoder) (bool, interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return false, tok, nil
+	}
+
+	switch delim {
+	case '[':
+		return true, nil, nil
+	case '{':
+		obj := make(map[string]interface{})
+		for dec.More() {
+			key, err := readKey(dec)
+			if err != nil {
+				return false, nil, err
+			}
+			var value interface{}
+			if err := dec.Decode(&value); err != nil {
+				return false, nil, err
+			}
+			obj[key] = value
+		}
+		return false, obj, expectDelim(dec, '}')
+	default:
+		return false, nil, fmt.Errorf("unexpected %q", delim)
+	}
+}
+
+// skipNull consumes the opening delimiter of the next value, reporting
+// whether the value was null instead
+func skipNull(dec *json.Decoder, open json.Delim) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == nil {
+		return true, nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != open {
+		return false, fmt.Errorf("expected %q, found %v", open, tok)
+	}
+	return false, nil
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, found %v", tok)
+	}
+	return key, nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, found %v", want, tok)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package keyscore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResultStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []Record
+		size     int64
+	}{
+		{
+			name: "results",
+			body: `{"results":{"email":[{"a":1},{"a":2}],"url":{"b":3},"none":null},"size":3,"took":7}`,
+			expected: []Record{
+				{Group: "email", Data: map[string]interface{}{"a": float64(1)}},
+				{Group: "email", Data: map[string]interface{}{"a": float64(2)}},
+				{Group: "url", Data: map[string]interface{}{"b": float64(3)}},
+			},
+			size: 3,
+		},
+		{
+			name: "pages",
+			body: `{"size":2,"extra":{"ignored":[1,2]},"pages":{"1":{"email":["x"]},"2":{"email":["y"]}}}`,
+			expected: []Record{
+				{Page: 1, Group: "email", Data: "x"},
+				{Page: 2, Group: "email", Data: "y"},
+			},
+			size: 2,
+		},
+	}
+
+	for _, test := range tests {
+		stream := newResultStream(io.NopCloser(strings.NewReader(test.body)))
+
+		var got []Record
+		for stream.Next() {
+			got = append(got, stream.Record())
+		}
+		stream.Close()
+
+		if err := stream.Err(); err != nil {
+			t.Fatalf("%s: stream returned error: %v", test.name, err)
+		}
+		if len(got) != len(test.expected) {
+			t.Fatalf("%s: got %d records, expected %d: %+v", test.name, len(got), len(test.expected), got)
+		}
+		for i := range got {
+			if got[i].Page != test.expected[i].Page || got[i].Group != test.expected[i].Group || !reflect.DeepEqual(got[i].Data, test.expected[i].Data) {
+				t.Errorf("%s: record %d = %+v, expected %+v", test.name, i, got[i], test.expected[i])
+			}
+		}
+		if stream.Size() != test.size {
+			t.Errorf("%s: Size() = %d, expected %d", test.name, stream.Size(), test.size)
+		}
+	}
+}
+
+func TestResultStream_MalformedBody(t *testing.T) {
+	stream := newResultStream(io.NopCloser(strings.NewReader(`{"results":{"email":[{"a":1},`)))
+	defer stream.Close()
+
+	count := 0
+	for stream.Next() {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("got %d records before the error, expected 1", count)
+	}
+	if stream.Err() == nil {
+		t.Error("stream did not report the truncated body")
+	}
+}
+
+func TestResultStream_CloseEarly(t *testing.T) {
+	stream := newResultStream(io.NopCloser(strings.NewReader(`{"results":{"email":[1,2,3,4]}}`)))
+
+	if !stream.Next() {
+		t.Fatal("stream yielded no records")
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if stream.Next() {
+		t.Error("stream yielded records after Close")
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("closing early reported error: %v", err)
+	}
+}
+
+func TestClient_SearchStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pagesize") != "5" {
+			t.Errorf("pagesize = %q, expected 5", r.URL.Query().Get("pagesize"))
+		}
+		w.Write([]byte(`{"pages":{"1":{"email":[1,2]}},"size":2}`))
+	}))
+	defer srv.Close()
+
+	pageSize := 5
+	stream, err := newTestClient(srv.URL).SearchStream(context.Background(), &SearchRequest{Terms: []string{"x"}}, &SearchPaginationParams{PageSize: &pageSize})
+	if err != nil {
+		t.Fatalf("SearchStream returned error: %v", err)
+	}
+	defer stream.Close()
+
+	count := 0
+	for stream.Next() {
+		count++
+	}
+	if count != 2 || stream.Err() != nil {
+		t.Errorf("streamed %d records (err %v), expected 2", count, stream.Err())
+	}
+}
+
+func TestClient_SearchStream_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv.URL).SearchStream(context.Background(), &SearchRequest{Terms: []string{"x"}}, nil); err == nil {
+		t.Error("SearchStream succeeded, expected an error")
+	}
+}